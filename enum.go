@@ -0,0 +1,74 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package params
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// -- enum Value, backed by a Go type
+type enumValue[T comparable] struct {
+	p       *T
+	allowed map[string]T
+}
+
+// EnumValue returns a Value which sets p to the member of allowed named by the
+// value given, for defining type-safe enums with Var, as in
+//   fs.Var(params.EnumValue(&color, colors), "color", "the color", "COLOR", 1)
+// Names not in allowed are an error.  The current value of p is the default.
+func EnumValue[T comparable](p *T, allowed map[string]T) Value {
+	return &enumValue[T]{p: p, allowed: allowed}
+}
+
+// EnumValueVar defines an enum command-line flag with specified name and usage
+// string.  The argument p points to a variable of any comparable type, which
+// is set to the member of allowed named by the value of the flag.  For a
+// FlagSet, use Var with EnumValue.
+func EnumValueVar[T comparable](p *T, name string, allowed map[string]T, usage, typeExp string) {
+	CommandLine.Var(EnumValue(p, allowed), name, usage, typeExp, 1)
+}
+
+func (e *enumValue[T]) Set(s []string) error {
+	v, ok := e.allowed[s[0]]
+	if !ok {
+		return fmt.Errorf("unknown value, must be one of: %s", strings.Join(e.names(), ", "))
+	}
+	*e.p = v
+	return nil
+}
+
+func (e *enumValue[T]) Get() interface{} { return *e.p }
+
+// String returns the name of the current value, the first in order if it
+// has more than one.
+func (e *enumValue[T]) String() string {
+	if e.p == nil {
+		return ""
+	}
+	for _, name := range e.names() {
+		if e.allowed[name] == *e.p {
+			return name
+		}
+	}
+	return fmt.Sprint(*e.p)
+}
+
+// Choices returns the names of the allowed values, to show in the help.
+func (e *enumValue[T]) Choices() []string { return e.names() }
+
+// names returns the names of the allowed values in order.
+func (e *enumValue[T]) names() []string {
+	var names []string
+	for name := range e.allowed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
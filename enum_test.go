@@ -0,0 +1,44 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.18
+// +build go1.18
+
+package params_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pschou/go-params"
+)
+
+type color int
+
+const (
+	red color = iota
+	green
+	blue
+)
+
+func TestEnumValue(t *testing.T) {
+	colors := map[string]color{"red": red, "green": green, "blue": blue, "verde": green}
+	fs := params.NewFlagSet("enum test", params.ContinueOnError)
+	fs.SetOutput(Discard{})
+	c := blue
+	fs.Var(params.EnumValue(&c, colors), "color", "the color", "COLOR", 1)
+	if got := fs.Lookup("color").DefValue; got != "blue" {
+		t.Errorf("DefValue = %q; want blue", got)
+	}
+	if err := fs.Parse([]string{"--color", "verde"}); err != nil || c != green {
+		t.Errorf("got %v, %v; want green", c, err)
+	}
+	if got := fs.Lookup("color").Value.String(); got != "green" {
+		t.Errorf("String() = %q; want green", got)
+	}
+	err := fs.Parse([]string{"--color", "pink"})
+	if err == nil || !strings.Contains(err.Error(), "blue, green, red, verde") {
+		t.Errorf("got error %v; want the allowed names", err)
+	}
+}
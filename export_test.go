@@ -14,7 +14,7 @@ var DefaultUsage = Usage
 // After calling ResetForTesting, parse errors in flag handling will not
 // exit the program.
 func ResetForTesting(usage func()) {
-	CommandLine = NewFlagSet(os.Args[0], ContinueOnError)
-	CommandLine.Usage = commandLineUsage
+	ResetCommandLine()
+	CommandLine.Init(os.Args[0], ContinueOnError)
 	Usage = usage
 }
@@ -23,7 +23,12 @@
 
 	Flag parsing stops after the terminator "--", or just before the first
 	non-flag argument ("-" is a non-flag argument) if the interspersed
-	argument to Parse is false.
+	argument to Parse is false.  Only the first "--" is the terminator, any
+	following "--" is kept as an argument, so "-- -- x" gives the arguments
+	"--" and "x".
+
+	A lone "-", by convention meaning standard input or output, is accepted
+	as the value of a flag, as in "--output -", see IsStdinDash.
 
 TODOs:
 - add flag for printing out all the possible flags for ease of tab completion
@@ -36,16 +41,21 @@ package params
 import (
 	"bytes"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"math"
 	"os"
 	"path"
+	"path/filepath"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
+	"unicode"
 	"unicode/utf8"
 
 	"github.com/mattn/go-runewidth"
@@ -56,9 +66,50 @@ import (
 // but no such flag is defined.
 var ErrHelp = errors.New("help requested")
 
+// ErrDumpFlags is the error returned after the flag values have been printed
+// for --dump-flags, see EnableDumpFlags.
+var ErrDumpFlags = errors.New("flag values dumped")
+
+// A ParseError is returned by Parse for an error about a flag, carrying the
+// details of the error as fields for structured logging.  The message of the
+// error is the same as before these were added.  For an error about several
+// flags, such as more than one required flag not being set, Names lists them
+// all and Name is the first.
+type ParseError struct {
+	Name   string   // the name of the flag, as given
+	Names  []string // the names of all the flags, for errors about several
+	Value  []string // the values given, if any
+	Reason string   // the kind of error, one of the Reason constants
+	Err    error    // the error from the Value or middleware, if any
+	msg    string
+}
+
+func (e *ParseError) Error() string { return e.msg }
+
+// Unwrap returns the error from the Value or middleware, if any.
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// Reasons given in a ParseError.
+const (
+	ReasonUndefined    = "undefined"    // the flag is not defined
+	ReasonMissing      = "missing"      // values needed were not given
+	ReasonUnwanted     = "unwanted"     // a value was given which was not wanted
+	ReasonEmpty        = "empty"        // an empty value was not accepted
+	ReasonInvalid      = "invalid"      // a value was not accepted
+	ReasonRequired     = "required"     // a required flag was not set
+	ReasonExperimental = "experimental" // an experimental flag was set without the gate
+	ReasonRequires     = "requires"     // a flag needed by another flag set was not set
+	ReasonExactlyOne   = "exactly-one"  // not exactly one flag of a group was set
+)
+
 // Word for default
 var Default = "Default: "
 
+// DefaultToken in a usage string is replaced by the default in the help, so
+// the default can be worded inline, as in "directory to use, {{default}} if
+// not given", in which case it is not also shown after the usage.
+const DefaultToken = "{{default}}"
+
 // -- Present Value
 type presentValue bool
 
@@ -85,6 +136,25 @@ type presentFlag interface {
 	IsPresentFlag() bool
 }
 
+// -- count Value, counting how many times the flag is seen
+type countValue int
+
+func newCountValue(p *int) *countValue {
+	*p = 0
+	return (*countValue)(p)
+}
+
+func (c *countValue) Set(s []string) error {
+	*c++
+	return nil
+}
+
+func (c *countValue) Get() interface{} { return int(*c) }
+
+func (c *countValue) String() string { return strconv.Itoa(int(*c)) }
+
+func (c *countValue) IsPresentFlag() bool { return true }
+
 // -- bool Value
 type boolValue bool
 
@@ -112,6 +182,46 @@ type boolFlag interface {
 	IsBoolFlag() bool
 }
 
+// optional interface to indicate flags which collect values and can be
+// emptied by the clear token
+type clearable interface {
+	Value
+	Clear()
+}
+
+// -- bool Value, also accepting yes/no, on/off and enabled/disabled
+type lenientBoolValue bool
+
+func newLenientBoolValue(val bool, p *bool) *lenientBoolValue {
+	*p = val
+	return (*lenientBoolValue)(p)
+}
+
+func (b *lenientBoolValue) Set(s []string) error {
+	v, err := parseLenientBool(s[0])
+	*b = lenientBoolValue(v)
+	return err
+}
+
+func (b *lenientBoolValue) Get() interface{} { return bool(*b) }
+
+func (b *lenientBoolValue) String() string { return fmt.Sprintf("%v", *b) }
+
+func (b *lenientBoolValue) IsBoolFlag() bool { return true }
+
+// parseLenientBool parses the words yes, y, on, enable and enabled as true,
+// and no, n, off, disable and disabled as false, in any case, and otherwise
+// anything accepted by strconv.ParseBool.
+func parseLenientBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "yes", "y", "on", "enable", "enabled":
+		return true, nil
+	case "no", "n", "off", "disable", "disabled":
+		return false, nil
+	}
+	return strconv.ParseBool(s)
+}
+
 // -- int Value
 type intValue int
 
@@ -130,6 +240,84 @@ func (i *intValue) Get() interface{} { return int(*i) }
 
 func (i *intValue) String() string { return fmt.Sprintf("%v", *i) }
 
+// -- int Value, where the value is optional
+type optionalIntValue struct {
+	p       *int
+	present int // value when the flag is given alone
+}
+
+func newOptionalIntValue(present, absent int, p *int) *optionalIntValue {
+	*p = absent
+	return &optionalIntValue{p: p, present: present}
+}
+
+func (i *optionalIntValue) Set(s []string) error {
+	if len(s) == 0 {
+		*i.p = i.present
+		return nil
+	}
+	v, err := strconv.ParseInt(s[0], 0, 64)
+	*i.p = int(v)
+	return err
+}
+
+func (i *optionalIntValue) Get() interface{} { return *i.p }
+
+func (i *optionalIntValue) String() string {
+	if i.p == nil {
+		return "0"
+	}
+	return fmt.Sprintf("%v", *i.p)
+}
+
+func (i *optionalIntValue) Accepts(s string) bool {
+	_, err := strconv.ParseInt(s, 0, 64)
+	return err == nil
+}
+
+// optionalArg is implemented by values whose flag takes an argument only when
+// one is given which the value Accepts.
+type optionalArg interface {
+	Accepts(string) bool
+}
+
+// The log levels set by a LogLevelVar flag, in increasing order of severity.
+const (
+	DebugLevel = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+var logLevelNames = []string{"debug", "info", "warn", "error"}
+
+// -- log level Value
+type logLevelValue int
+
+func newLogLevelValue(val int, p *int) *logLevelValue {
+	*p = val
+	return (*logLevelValue)(p)
+}
+
+func (l *logLevelValue) Set(s []string) error {
+	for i, name := range logLevelNames {
+		if strings.EqualFold(s[0], name) {
+			*l = logLevelValue(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown level, must be one of: %s", strings.Join(logLevelNames, ", "))
+}
+
+func (l *logLevelValue) Get() interface{} { return int(*l) }
+
+func (l *logLevelValue) String() string {
+	if int(*l) >= 0 && int(*l) < len(logLevelNames) {
+		return logLevelNames[*l]
+	}
+	return fmt.Sprintf("%v", int(*l))
+}
+
 // -- int64 Value
 type int64Value int64
 
@@ -166,6 +354,73 @@ func (i *uintValue) Get() interface{} { return uint(*i) }
 
 func (i *uintValue) String() string { return fmt.Sprintf("%v", *i) }
 
+// -- int Values, limited to a range
+type intRangeValue struct {
+	*intValue
+	min, max int
+}
+
+func (i *intRangeValue) Set(s []string) error {
+	old := *i.intValue
+	if err := i.intValue.Set(s); err != nil {
+		*i.intValue = old
+		return err
+	}
+	if v := int(*i.intValue); v < i.min || v > i.max {
+		*i.intValue = old
+		return fmt.Errorf("value %d out of range %s", v, i.Range())
+	}
+	return nil
+}
+
+func (i *intRangeValue) Range() string { return fmt.Sprintf("[%d,%d]", i.min, i.max) }
+
+type int64RangeValue struct {
+	*int64Value
+	min, max int64
+}
+
+func (i *int64RangeValue) Set(s []string) error {
+	old := *i.int64Value
+	if err := i.int64Value.Set(s); err != nil {
+		*i.int64Value = old
+		return err
+	}
+	if v := int64(*i.int64Value); v < i.min || v > i.max {
+		*i.int64Value = old
+		return fmt.Errorf("value %d out of range %s", v, i.Range())
+	}
+	return nil
+}
+
+func (i *int64RangeValue) Range() string { return fmt.Sprintf("[%d,%d]", i.min, i.max) }
+
+type uintRangeValue struct {
+	*uintValue
+	min, max uint
+}
+
+func (i *uintRangeValue) Set(s []string) error {
+	old := *i.uintValue
+	if err := i.uintValue.Set(s); err != nil {
+		*i.uintValue = old
+		return err
+	}
+	if v := uint(*i.uintValue); v < i.min || v > i.max {
+		*i.uintValue = old
+		return fmt.Errorf("value %d out of range %s", v, i.Range())
+	}
+	return nil
+}
+
+func (i *uintRangeValue) Range() string { return fmt.Sprintf("[%d,%d]", i.min, i.max) }
+
+// optional interface to indicate values limited to a range, shown in the help
+type ranged interface {
+	Value
+	Range() string
+}
+
 // -- uint64 Value
 type uint64Value uint64
 
@@ -201,6 +456,30 @@ func (s *stringValue) Get() interface{} { return string(*s) }
 
 func (s *stringValue) String() string { return fmt.Sprintf("%s", *s) }
 
+// -- string Value, limited to a set of choices
+type enumStringValue struct {
+	*stringValue
+	allowed []string
+}
+
+func (s *enumStringValue) Set(val []string) error {
+	for _, a := range s.allowed {
+		if val[0] == a {
+			return s.stringValue.Set(val)
+		}
+	}
+	return fmt.Errorf("unknown value, must be one of: %s", strings.Join(s.allowed, ", "))
+}
+
+func (s *enumStringValue) Choices() []string { return s.allowed }
+
+// optional interface to indicate values limited to a set of choices, shown in
+// the help
+type choices interface {
+	Value
+	Choices() []string
+}
+
 // -- StringSliceValue Value
 type stringSliceValue []string
 
@@ -214,1251 +493,5009 @@ func (s *stringSliceValue) Set(val []string) error {
 	return nil
 }
 
+func (s *stringSliceValue) Clear() { *s = stringSliceValue{} }
+
 func (s *stringSliceValue) Get() interface{} { return ([]string)(*s) }
 
 func (s *stringSliceValue) String() string { return fmt.Sprintf("%q", *s) }
 
-// -- float64 Value
-type float64Value float64
+// -- IndexedStringSliceValue Value, also set by index as in --item[1]
+type indexedStringSliceValue []string
 
-func newFloat64Value(val float64, p *float64) *float64Value {
+func newIndexedStringSliceValue(val []string, p *([]string)) *indexedStringSliceValue {
 	*p = val
-	return (*float64Value)(p)
+	return (*indexedStringSliceValue)(p)
 }
 
-func (f *float64Value) Set(s []string) error {
-	v, err := strconv.ParseFloat(s[0], 64)
-	*f = float64Value(v)
-	return err
+func (s *indexedStringSliceValue) Set(val []string) error {
+	*s = append(*s, val...)
+	return nil
 }
 
-func (f *float64Value) Get() interface{} { return float64(*f) }
+// SetNamed sets the element at the index in name, such as 1 for item[1],
+// growing the slice as needed, or appends if there is no index.
+func (s *indexedStringSliceValue) SetNamed(name string, val []string) error {
+	_, i, ok := splitIndex(name)
+	if !ok {
+		return s.Set(val)
+	}
+	if i < 0 {
+		return fmt.Errorf("index %d is negative", i)
+	}
+	for len(*s) <= i {
+		*s = append(*s, "")
+	}
+	(*s)[i] = val[0]
+	return nil
+}
 
-func (f *float64Value) String() string { return fmt.Sprintf("%v", *f) }
+func (s *indexedStringSliceValue) IsIndexed() bool { return true }
 
-// -- time.Duration Value
-type durationValue time.Duration
+func (s *indexedStringSliceValue) Clear() { *s = indexedStringSliceValue{} }
 
-func newDurationValue(val time.Duration, p *time.Duration) *durationValue {
+func (s *indexedStringSliceValue) Get() interface{} { return ([]string)(*s) }
+
+func (s *indexedStringSliceValue) String() string { return fmt.Sprintf("%q", *s) }
+
+// optional interface to indicate values which may be set by index, as in
+// --item[1]
+type indexedFlag interface {
+	Value
+	IsIndexed() bool
+}
+
+// splitIndex splits a name with an index, such as item[1], into the name and
+// the index, and reports whether it had one.
+func splitIndex(name string) (base string, index int, ok bool) {
+	i := strings.LastIndexByte(name, '[')
+	if i <= 0 || !strings.HasSuffix(name, "]") {
+		return name, 0, false
+	}
+	index, err := strconv.Atoi(name[i+1 : len(name)-1])
+	if err != nil {
+		return name, 0, false
+	}
+	return name[:i], index, true
+}
+
+// -- IntSliceValue Value
+type intSliceValue []int
+
+func newIntSliceValue(val []int, p *([]int)) *intSliceValue {
 	*p = val
-	return (*durationValue)(p)
+	return (*intSliceValue)(p)
 }
 
-func (d *durationValue) Set(s []string) error {
-	v, err := str2duration.Str2Duration(s[0])
-	*d = durationValue(v)
-	return err
+func (s *intSliceValue) Set(val []string) error {
+	ints := make([]int, 0, len(val))
+	for _, v := range val {
+		i, err := strconv.ParseInt(v, 0, strconv.IntSize)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", v)
+		}
+		ints = append(ints, int(i))
+	}
+	*s = append(*s, ints...)
+	return nil
 }
 
-func (d *durationValue) Get() interface{} { return time.Duration(*d) }
+func (s *intSliceValue) Clear() { *s = intSliceValue{} }
 
-func (d *durationValue) String() string { return (*time.Duration)(d).String() }
+func (s *intSliceValue) Get() interface{} { return ([]int)(*s) }
 
-type flagFuncValue func([]string) error
+func (s *intSliceValue) String() string { return fmt.Sprintf("%v", []int(*s)) }
 
-func (f flagFuncValue) Set(s []string) error { return f(s) }
+// -- Float64SliceValue Value
+type float64SliceValue []float64
 
-func (f flagFuncValue) String() string { return "" }
+func newFloat64SliceValue(val []float64, p *([]float64)) *float64SliceValue {
+	*p = val
+	return (*float64SliceValue)(p)
+}
 
-// Value is the interface to the dynamic value stored in a flag.
-// (The default value is represented as a string.)
-type Value interface {
-	String() string
-	Set([]string) error
+func (s *float64SliceValue) Set(val []string) error {
+	floats := make([]float64, 0, len(val))
+	for _, v := range val {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", v)
+		}
+		floats = append(floats, f)
+	}
+	*s = append(*s, floats...)
+	return nil
 }
 
-// Getter is an interface that allows the contents of a Value to be retrieved.
-// It wraps the Value interface, rather than being part of it, because it
-// appeared after Go 1 and its compatibility rules. All Value types provided
-// by this package satisfy the Getter interface.
-type Getter interface {
-	Value
-	Get() interface{}
+func (s *float64SliceValue) Clear() { *s = float64SliceValue{} }
+
+func (s *float64SliceValue) Get() interface{} { return ([]float64)(*s) }
+
+func (s *float64SliceValue) String() string { return fmt.Sprintf("%v", []float64(*s)) }
+
+// -- DurationSliceValue Value
+type durationSliceValue []time.Duration
+
+func newDurationSliceValue(val []time.Duration, p *([]time.Duration)) *durationSliceValue {
+	*p = val
+	return (*durationSliceValue)(p)
 }
 
-// ErrorHandling defines how to handle flag parsing errors.
-type ErrorHandling int
+func (s *durationSliceValue) Set(val []string) error {
+	durations := make([]time.Duration, 0, len(val))
+	for _, v := range val {
+		d, err := str2duration.Str2Duration(v)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", v)
+		}
+		durations = append(durations, d)
+	}
+	*s = append(*s, durations...)
+	return nil
+}
 
-const (
-	ContinueOnError ErrorHandling = iota
-	ExitOnError
-	PanicOnError
-)
+func (s *durationSliceValue) Clear() { *s = durationSliceValue{} }
 
-// A FlagSet represents a set of defined flags.
-type FlagSet struct {
-	// Usage is the function called when an error occurs while parsing flags.
-	// The field is a function (not a method) that may be changed to point to
-	// a custom error handler.
-	Usage func()
+func (s *durationSliceValue) Get() interface{} { return ([]time.Duration)(*s) }
 
-	name             string
-	Title            string
-	parsed           bool
-	actual           []*Flag
-	formal           []*Flag
-	nameList         []string
-	Params           []Param // argument parsers for after flags
-	args             []string
-	procArgs         []string // arguments being processed (gnu only)
-	procFlag         string   // flag being processed (gnu only)
-	allowIntersperse bool     // (gnu only)
-	exitOnError      bool     // does the program exit if there's an error?
-	errorHandling    ErrorHandling
-	output           io.Writer // nil means stderr; use out() accessor
-	curGrouping      string
-	mulock           *sync.Mutex
+func (s *durationSliceValue) String() string { return fmt.Sprintf("%v", []time.Duration(*s)) }
 
-	// SetUsageIndent tells the DefaultPrinter how many spaces to add to before
-	// printing the usage for each flag.  By default this is 0 and determined by
-	// the maximum comma seperated name length.
-	Indent      int // beginning of line space
-	UsageIndent int // usage column position
+// -- StringMapValue Value
+type stringMapValue map[string]string
 
-	UsageSpace int // minimum number of spaces required before usage
-	TypeSpace  int // minimum number of spaces required before input type string
+func newStringMapValue(val map[string]string, p *map[string]string) *stringMapValue {
+	*p = val
+	return (*stringMapValue)(p)
+}
 
-	ShowGroupings   bool                     // Show the flags in groups
-	GroupingHeaders func(string, int) string // function used to generate headers, like "Options:"
+// Set adds each key=value pair to the map, replacing the value of a key
+// given before so the last one wins.
+func (s *stringMapValue) Set(val []string) error {
+	for _, v := range val {
+		if !strings.Contains(v, "=") {
+			return fmt.Errorf("missing = in %q, must be key=value", v)
+		}
+	}
+	if *s == nil {
+		*s = stringMapValue{}
+	}
+	for _, v := range val {
+		kv := strings.SplitN(v, "=", 2)
+		(*s)[kv[0]] = kv[1]
+	}
+	return nil
+}
 
-	ShowDefaultVal bool // Display the (Default: "") example
+func (s *stringMapValue) Clear() { *s = stringMapValue{} }
 
-	// FlagKnownAs allows different projects to customise what their flags are
-	// known as, e.g. 'flag', 'option', 'item'. All error/log messages
-	// will use that name when referring to an individual items/flags in this set.
-	// For example, if this value is 'option', the default message 'value for param'
-	// will become 'value for option'.
-	// Default value is 'flag'.
-	FlagKnownAs string
+func (s *stringMapValue) Get() interface{} { return (map[string]string)(*s) }
+
+func (s *stringMapValue) String() string {
+	pairs := make([]string, 0, len(*s))
+	for k, v := range *s {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return fmt.Sprintf("%q", pairs)
 }
 
-// A Flag represents the state of a flag.
-type Flag struct {
-	Name         []string                      // name as it appears on command line
-	Usage        string                        // help message
-	Value        Value                         // value as set
-	DefValue     string                        // default value
-	TypeExpected string                        // helpful hint on what is expected
-	ArgsNeeded   int                           // arg count wanted
-	Grouping     string                        // organize flags into groups
-	Options      func(string, string) []string // function to return possible outcomes for bash completion
+// -- path Value, a string checked against the filesystem
+type pathValue struct {
+	p     *string
+	check func(string) error
 }
 
-type Param struct {
-	Usage        string                                                  // help message
-	Value        Value                                                   // value as set
-	DefValue     string                                                  // default value
-	TypeExpected string                                                  // helpful hint on what is expected
-	Options      []string                                                // Available options for tab-fill
-	OptionsFunc  func(flagsSeen []Flag, argsSeen []string) []string      // Get options for bash completion
-	Test         func(flagsSeen []Flag, argsSeen []string) (bool, error) // Options
+func newPathValue(val string, p *string, check func(string) error) *pathValue {
+	*p = val
+	return &pathValue{p: p, check: check}
 }
 
-// splitOn, reads out a string and returns a slice
-func splitOn(str string, c rune, count int) (out []string) {
-	var line bytes.Buffer
-	for i := 0; i < len(str); {
-		r, size := utf8.DecodeRuneInString(str[i:])
-		if r == c {
-			if line.Len() == 0 {
-				continue
-			}
-			out = append(out, line.String())
-			line.Reset()
-			if len(out) == count-1 {
-				line.WriteString(str[i+size:])
-				break
-			}
-		} else {
-			line.WriteRune(r)
-		}
-		i += size
+func (s *pathValue) Set(val []string) error {
+	if err := s.check(val[0]); err != nil {
+		return err
 	}
-	if line.Len() > 0 {
-		out = append(out, line.String())
+	*s.p = val[0]
+	return nil
+}
+
+func (s *pathValue) Get() interface{} { return *s.p }
+
+func (s *pathValue) String() string {
+	if s.p == nil {
+		return ""
 	}
-	return
+	return *s.p
 }
 
-// sortFlags returns the flags as a slice in lexicographical sorted order.
-func sortFlags(flags []*Flag) []*Flag {
-	list := make(flagsByName, len(flags))
-	copy(list, flags)
-	//i := 0
-	//for _, f := range flags {
-	//	list[i] = f.Name[0]
-	//	i++
-	//}
-	sort.Sort(list)
-	//result := make([]*Flag, len(list))
-	//for i, name := range list {
-	//	result[i] = flags[name]
-	//}
-	return list
+// checkExistingFile ensures the path exists and is not a directory.
+func checkExistingFile(name string) error {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return fmt.Errorf("%s is a directory", name)
+	}
+	return nil
 }
 
-// Output returns the destination for usage and error messages. os.Stderr is returned if
-// output was not set or was set to nil.
-func (f *FlagSet) Output() io.Writer {
-	if f.output == nil {
-		return os.Stderr
+// checkExistingDir ensures the path exists and is a directory.
+func checkExistingDir(name string) error {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return err
 	}
-	return f.output
+	if !fi.IsDir() {
+		return fmt.Errorf("%s is not a directory", name)
+	}
+	return nil
 }
 
-// Name returns the name of the flag set.
-func (f *FlagSet) Name() string {
-	return f.name
+// checkNewFile ensures the directory the path is to be created in exists.
+func checkNewFile(name string) error {
+	if err := checkExistingDir(filepath.Dir(name)); err != nil {
+		return err
+	}
+	if fi, err := os.Stat(name); err == nil && fi.IsDir() {
+		return fmt.Errorf("%s is a directory", name)
+	}
+	return nil
 }
 
-// ErrorHandling returns the error handling behavior of the flag set.
-func (f *FlagSet) ErrorHandling() ErrorHandling {
-	return f.errorHandling
+// -- stringListValue Value
+type stringListValue []string
+
+func newStringListValue(val []string, p *([]string)) *stringListValue {
+	*p = val
+	return (*stringListValue)(p)
 }
 
-// SetOutput sets the destination for usage and error messages.
-// If output is nil, os.Stderr is used.
-func (f *FlagSet) SetOutput(output io.Writer) {
-	f.output = output
+func (s *stringListValue) Set(val []string) error {
+	for _, v := range val {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*s = append(*s, item)
+			}
+		}
+	}
+	return nil
 }
 
-// GroupingSet creates a grouping set for new flags added.  This is helpful if
-// there are many flags and they can be organized in smaller groupings.
-func GroupingSet(grouping string) {
-	CommandLine.curGrouping = grouping
+func (s *stringListValue) Clear() { *s = stringListValue{} }
+
+func (s *stringListValue) Get() interface{} { return ([]string)(*s) }
+
+func (s *stringListValue) String() string { return fmt.Sprintf("%q", *s) }
+
+// -- float64 Value
+type float64Value float64
+
+func newFloat64Value(val float64, p *float64) *float64Value {
+	*p = val
+	return (*float64Value)(p)
 }
 
-// GroupingSet creates a grouping set for new flags added.  This is helpful if
-// there are many flags and they can be organized in smaller groupings.
-func (f *FlagSet) GroupingSet(grouping string) {
-	f.curGrouping = grouping
+func (f *float64Value) Set(s []string) error {
+	v, err := strconv.ParseFloat(s[0], 64)
+	*f = float64Value(v)
+	return err
 }
 
-// SetAllowIntersperse tells the parser if flags can be interspersed with other
-// arguments.  If AllowIntersperse is set to true, arguments and flags can be
-// interspersed, that is flags can follow positional arguments.
-//
-// Example of true:
-//   prog -flag1 input1 input2 -flag2
-// Example of false: (default)
-//   prog -flag1 -flag2 input1 input2
-func (f *FlagSet) SetAllowIntersperse(allowIntersperse bool) {
-	f.allowIntersperse = allowIntersperse
+func (f *float64Value) Get() interface{} { return float64(*f) }
+
+func (f *float64Value) String() string { return fmt.Sprintf("%v", *f) }
+
+// -- float64 Value with a unit, converted to the base unit
+type unitFloatValue struct {
+	p     *float64
+	units map[string]float64 // multiplier for each unit
+	base  float64            // multiplier for a value without a unit
 }
 
-// SetAllowIntersperse tells the parser if flags can be interspersed with other
-// arguments.  If AllowIntersperse is set to true, arguments and flags can be
-// interspersed, that is flags can follow positional arguments.
-//
-// Example of true:
-//   prog -flag1 input1 input2 -flag2
-// Example of false: (default)
-//   prog -flag1 -flag2 input1 input2
-func SetAllowIntersperse(allowIntersperse bool) {
-	CommandLine.allowIntersperse = allowIntersperse
+func newUnitFloatValue(val float64, p *float64, units map[string]float64, base float64) *unitFloatValue {
+	*p = val
+	return &unitFloatValue{p: p, units: units, base: base}
 }
 
-// VisitAll visits the flags in lexicographical order, calling fn for each.
-// It visits all flags, even those not set.
-func (f *FlagSet) VisitAll(fn func(*Flag)) {
-	for _, flag := range sortFlags(f.formal) {
-		fn(flag)
+func (u *unitFloatValue) Set(s []string) error {
+	str := strings.TrimSpace(s[0])
+	if v, err := strconv.ParseFloat(str, 64); err == nil {
+		*u.p = v * u.base
+		return nil
+	}
+	var names []string
+	for name := range u.units {
+		names = append(names, name)
+	}
+	// try the longest units first, so "ms" is not taken as "s"
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) > len(names[j])
+		}
+		return names[i] < names[j]
+	})
+	for _, name := range names {
+		if num := strings.TrimSuffix(str, name); num != str {
+			v, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+			if err != nil {
+				return err
+			}
+			*u.p = v * u.units[name]
+			return nil
+		}
 	}
+	sort.Strings(names)
+	return fmt.Errorf("unknown unit, must be one of: %s", strings.Join(names, ", "))
 }
 
-// VisitAll visits the command-line flags in lexicographical order, calling
-// fn for each.  It visits all flags, even those not set.
-func VisitAll(fn func(*Flag)) {
-	CommandLine.VisitAll(fn)
+func (u *unitFloatValue) Get() interface{} { return *u.p }
+
+func (u *unitFloatValue) String() string {
+	if u.p == nil {
+		return "0"
+	}
+	return strconv.FormatFloat(*u.p, 'g', -1, 64)
 }
 
-// Visit visits the flags in lexicographical order, calling fn for each.
-// It visits only those flags that have been set.
-func (f *FlagSet) Visit(fn func(*Flag)) {
-	for _, flag := range sortFlags(f.actual) {
-		fn(flag)
+// -- time.Duration Value
+type durationValue time.Duration
+
+func newDurationValue(val time.Duration, p *time.Duration) *durationValue {
+	*p = val
+	return (*durationValue)(p)
+}
+
+func (d *durationValue) Set(s []string) error {
+	v, err := str2duration.Str2Duration(s[0])
+	*d = durationValue(v)
+	return err
+}
+
+func (d *durationValue) Get() interface{} { return time.Duration(*d) }
+
+func (d *durationValue) String() string { return (*time.Duration)(d).String() }
+
+// -- time.Duration Value, with the zero default shown by a word
+type zeroDurationValue struct {
+	*durationValue
+	zero string // shown for a zero default
+}
+
+func (d *zeroDurationValue) DefaultString() string {
+	if *d.durationValue == 0 {
+		return d.zero
 	}
+	return d.String()
 }
 
-// Visit visits the command-line flags in lexicographical order, calling fn
-// for each.  It visits only those flags that have been set.
-func Visit(fn func(*Flag)) {
-	CommandLine.Visit(fn)
+// -- time.Duration Value, limited to some units
+type unitDurationValue struct {
+	*durationValue
+	units []string // units allowed
 }
 
-// Lookup returns the Flag structure of the named flag, returning nil if none exists.
-func (f *FlagSet) Lookup(name string) *Flag {
-	if f.mulock == nil {
-		f.mulock = new(sync.Mutex)
+func (d *unitDurationValue) Set(s []string) error {
+	if err := checkUnits(s[0], d.units); err != nil {
+		return err
 	}
-	f.mulock.Lock()
-	defer f.mulock.Unlock()
-	for _, flag := range f.formal {
-		for _, n := range flag.Name {
-			if name == n {
-				return flag
+	return d.durationValue.Set(s)
+}
+
+// checkUnits ensures each unit in the duration s, such as the "ms" in "100ms",
+// is one of units.
+func checkUnits(s string, units []string) error {
+	for i := 0; i < len(s); {
+		r, n := utf8.DecodeRuneInString(s[i:])
+		if !unicode.IsLetter(r) {
+			i += n
+			continue
+		}
+		j := i
+		for j < len(s) {
+			r, n := utf8.DecodeRuneInString(s[j:])
+			if !unicode.IsLetter(r) {
+				break
 			}
+			j += n
 		}
+		unit, ok := s[i:j], false
+		for _, u := range units {
+			if unit == u {
+				ok = true
+			}
+		}
+		if !ok {
+			return fmt.Errorf("unit %q not allowed, must be one of: %s", unit, strings.Join(units, ", "))
+		}
+		i = j
 	}
 	return nil
 }
 
-// Lookup returns the Flag structure of the named command-line flag,
-// returning nil if none exists.
-func Lookup(name string) *Flag {
-	return CommandLine.Lookup(name)
+// -- time.Duration Value, also accepting keywords such as "never"
+type keywordDurationValue struct {
+	*durationValue
+	keywords map[string]time.Duration
 }
 
-// Set sets the value of the named flag.
-func (f *FlagSet) Set(name string, value []string) error {
-	f.mulock.Lock()
-	defer f.mulock.Unlock()
-	var flag *Flag
-	for _, flagTest := range f.formal {
-		for _, n := range flagTest.Name {
-			if n == name {
-				flag = flagTest
-			}
-		}
-	}
-	if flag == nil {
-		return fmt.Errorf("no such %v -%v", f.FlagKnownAs, name)
-	}
-	err := flag.Value.Set(value)
-	if err != nil {
-		return err
+func (d *keywordDurationValue) Set(s []string) error {
+	if v, ok := d.keywords[s[0]]; ok {
+		*d.durationValue = durationValue(v)
+		return nil
 	}
-	if f.actual == nil {
-		f.actual = make([]*Flag, 0)
+	if err := d.durationValue.Set(s); err != nil {
+		return fmt.Errorf("must be a duration or one of: %s", strings.Join(d.names(), ", "))
 	}
-	f.actual = append(f.actual, flag)
 	return nil
 }
 
-// Set sets the value of the named command-line flag.
-func Set(name string, value []string) error {
-	return CommandLine.Set(name, value)
+// String shows the keyword for the value, if there is one.
+func (d *keywordDurationValue) String() string {
+	for _, name := range d.names() {
+		if d.keywords[name] == time.Duration(*d.durationValue) {
+			return name
+		}
+	}
+	return d.durationValue.String()
 }
 
-/*
-// flagsByLength is a slice of flags implementing sort.Interface,
-// sorting primarily by the length of the flag, and secondarily
-// alphabetically.
-type flagsByLength []*Flag
-
-func (f flagsByLength) Less(i, j int) bool {
-	s1, s2 := f[i].Name, f[j].Name
-	if len(s1) != len(s2) {
-		return len(s1) < len(s2)
+// names returns the keywords in order.
+func (d *keywordDurationValue) names() []string {
+	var names []string
+	for name := range d.keywords {
+		names = append(names, name)
 	}
-	return s1 < s2
+	sort.Strings(names)
+	return names
 }
-func (f flagsByLength) Swap(i, j int) {
-	f[i], f[j] = f[j], f[i]
+
+// -- time.Duration Value, also accepting ISO-8601
+type isoDurationValue time.Duration
+
+func newISODurationValue(val time.Duration, p *time.Duration) *isoDurationValue {
+	*p = val
+	return (*isoDurationValue)(p)
 }
-func (f flagsByLength) Len() int {
-	return len(f)
+
+func (d *isoDurationValue) Set(s []string) error {
+	v, err := str2duration.Str2Duration(s[0])
+	if err != nil {
+		var isoErr error
+		if v, isoErr = parseISODuration(s[0]); isoErr != nil {
+			return err
+		}
+	}
+	*d = isoDurationValue(v)
+	return nil
 }
-*/
 
-// flagsByName is a slice of slices of flags implementing sort.Interface,
-// alphabetically sorting by the name of the first flag in each slice.
-type flagsByName []*Flag
+func (d *isoDurationValue) Get() interface{} { return time.Duration(*d) }
 
-func (f flagsByName) Less(i, j int) bool {
-	var a, b int
-	if len(f[i].Name) > 1 && len(f[i].Name[0]) == 1 && len(f[i].Name[1]) > 1 {
-		a = 1
+func (d *isoDurationValue) String() string { return (*time.Duration)(d).String() }
+
+// parseISODuration parses an ISO-8601 duration such as "PT1H30M" or "P1DT12H".
+// A year is taken as 365 days and a month as 30 days.
+func parseISODuration(s string) (time.Duration, error) {
+	str, neg := s, false
+	if strings.HasPrefix(str, "-") {
+		str, neg = str[1:], true
 	}
-	if len(f[j].Name) > 1 && len(f[j].Name[0]) == 1 && len(f[i].Name[1]) > 1 {
-		b = 1
+	if len(str) < 2 || (str[0] != 'P' && str[0] != 'p') {
+		return 0, fmt.Errorf("invalid ISO-8601 duration %q", s)
 	}
-	return f[i].Name[a] < f[j].Name[b]
-}
-func (f flagsByName) Swap(i, j int) {
-	f[i], f[j] = f[j], f[i]
-}
-func (f flagsByName) Len() int {
-	return len(f)
-}
-
-/*
-func (f *FlagSet) ptrToVal(ptr string) (ret Value) {
-	f.VisitAll(func(f *Flag) {
-		if fmt.Sprintf("%#v") == ptr {
-			ret = f.Value
+	const day = 24 * time.Hour
+	var d time.Duration
+	var inTime, seen bool
+	num := ""
+	for _, r := range str[1:] {
+		if r >= '0' && r <= '9' || r == '.' || r == ',' {
+			num += string(r)
+			continue
 		}
-	})
-	return
+		if r == 'T' || r == 't' {
+			if inTime || num != "" {
+				return 0, fmt.Errorf("invalid ISO-8601 duration %q", s)
+			}
+			inTime = true
+			continue
+		}
+		if num == "" {
+			return 0, fmt.Errorf("invalid ISO-8601 duration %q", s)
+		}
+		v, err := strconv.ParseFloat(strings.Replace(num, ",", ".", 1), 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid ISO-8601 duration %q", s)
+		}
+		var unit time.Duration
+		switch {
+		case !inTime && (r == 'Y' || r == 'y'):
+			unit = 365 * day
+		case !inTime && (r == 'M' || r == 'm'):
+			unit = 30 * day
+		case !inTime && (r == 'W' || r == 'w'):
+			unit = 7 * day
+		case !inTime && (r == 'D' || r == 'd'):
+			unit = day
+		case inTime && (r == 'H' || r == 'h'):
+			unit = time.Hour
+		case inTime && (r == 'M' || r == 'm'):
+			unit = time.Minute
+		case inTime && (r == 'S' || r == 's'):
+			unit = time.Second
+		default:
+			return 0, fmt.Errorf("invalid ISO-8601 duration %q", s)
+		}
+		d += time.Duration(v * float64(unit))
+		num, seen = "", true
+	}
+	if num != "" || !seen {
+		return 0, fmt.Errorf("invalid ISO-8601 duration %q", s)
+	}
+	if neg {
+		d = -d
+	}
+	return d, nil
 }
-*/
 
-func rlen(s string) int {
-	return utf8.RuneCount([]byte(s))
+// -- func Value, Get returns the values last handed to the func
+type flagFuncValue struct {
+	fn      func([]string) error
+	last    []string // values of the last successful call
+	display string   // default shown in help
 }
 
-// PrintDefaults prints, to standard error unless configured
-// otherwise, the default values of all defined flags in the set.
-// If there is more than one name for a given flag, the usage information and
-// default value from the shortest will be printed (or the least alphabetically
-// if there are several equally short flag names).
-func (f *FlagSet) PrintDefaults() {
-	//var maxLen int
-	var haveMultiple, haveSingleChar bool
-	// group together all flags for a given value
-	var flags [](*Flag)
-	var nameAndTypeLen []int
-	var groupings []string
-	var groupingsCount = map[string]int{}
-
-	f.mulock.Lock()
-loop_formals:
-	for _, flag := range f.formal {
-		for _, grp := range groupings {
-			if grp == flag.Grouping {
-				groupingsCount[flag.Grouping]++
-				continue loop_formals
-			}
-		}
-		groupings = append(groupings, flag.Grouping)
-		groupingsCount[flag.Grouping] = 1
+func (f *flagFuncValue) Set(s []string) error {
+	if err := f.fn(s); err != nil {
+		return err
 	}
-	f.mulock.Unlock()
+	f.last = s
+	return nil
+}
 
-	var avgLen float64
-	//var uniqueFlag = make(map[string]interface{})
-	f.VisitAll(func(flag *Flag) {
-		//if _, ok := uniqueFlag[flag.Name[0]]; !ok {
-		//uniqueFlag[flag.Name[0]] = nil
-		flags = append(flags, flag)
+func (f *flagFuncValue) Get() interface{} { return f.last }
 
-		// Set boolean if we have multiple flags set
-		if len(flag.Name) > 1 {
-			haveMultiple = true
-		}
-		// Check if we have single char flags
-		for _, name := range flag.Name {
-			if rlen(name) == 1 {
-				haveSingleChar = true
-			}
-		}
+func (f *flagFuncValue) String() string { return f.display }
 
-		if f.UsageIndent == 0 {
-			myLen := 2*(len(flag.Name)-1) + f.UsageSpace + f.Indent
-			for _, name := range flag.Name {
-				myLen += runewidth.StringWidth(name)
-			}
+type flagFuncIndexedValue struct {
+	fn   func(int, []string) error
+	n    int      // occurrences seen
+	last []string // values of the last successful call
+}
 
-			// Math to determine width needed
-			if flag.TypeExpected != "" {
-				withTypeLen := myLen + f.TypeSpace + runewidth.StringWidth(flag.TypeExpected)
-				nameAndTypeLen = append(nameAndTypeLen, withTypeLen)
-				avgLen += float64(withTypeLen)
-			} else {
-				nameAndTypeLen = append(nameAndTypeLen, myLen)
-				avgLen += float64(myLen)
-			}
-		}
-		//}
-	})
+func (f *flagFuncIndexedValue) Set(s []string) error {
+	i := f.n
+	f.n++
+	if err := f.fn(i, s); err != nil {
+		return err
+	}
+	f.last = s
+	return nil
+}
 
-	var usageIndent int
+func (f *flagFuncIndexedValue) Get() interface{} { return f.last }
 
-	if f.UsageIndent == 0 {
-		avgLen /= float64(len(nameAndTypeLen))
-		var stdDev float64
-		for _, l := range nameAndTypeLen {
-			stdDev += math.Pow(math.Abs(float64(l)-avgLen), 0.5)
-		}
-		stdDev = math.Pow(stdDev/float64(len(nameAndTypeLen)), 2)
+func (f *flagFuncIndexedValue) String() string { return "" }
 
-		usageIndent = int(avgLen + stdDev*1.6)
-	} else {
-		usageIndent = f.UsageIndent
-	}
+// -- std Value, adapting a Value of the standard flag package
+type stdValue struct {
+	v flag.Value
+}
 
-	//sort.Sort(flags)
+// WrapStdValue adapts v, a Value of the standard flag package such as those
+// provided by many libraries, to a Value of this package.  The first of the
+// values is handed to v.Set, or "true" when there are none, as for a bool
+// flag defined with argsNeeded of 0.  Get returns nil if v is not a Getter.
+func WrapStdValue(v flag.Value) Value {
+	return &stdValue{v: v}
+}
 
-	// sort the output flags by shortest name for each group.
-	//var byName flagsByName
-	//for _, f := range flags {
-	//	sort.Sort(f)
-	//	byName = append(byName, f)
-	//}
-	pad := "\n"
-	for len(pad) <= usageIndent {
-		pad += " "
+func (s *stdValue) Set(val []string) error {
+	if len(val) == 0 {
+		return s.v.Set("true")
 	}
+	return s.v.Set(val[0])
+}
 
-	var line bytes.Buffer
-	for _, grp := range groupings {
-		if f.ShowGroupings {
-			// Print group headers
-			fmt.Fprintln(f.Output(), f.GroupingHeaders(grp, groupingsCount[grp]))
-			/*plural := ""
-			if groupingsCount[grp] > 1 {
-				plural = "s"
-			}
-			if grp == "" {
-				fmt.Fprintf(f.Output(), "Option%s:\n", plural)
-			} else {
-				fmt.Fprintf(f.Output(), "%s option%s:\n", grp, plural)
-			}*/
-		}
+func (s *stdValue) Get() interface{} {
+	if g, ok := s.v.(flag.Getter); ok {
+		return g.Get()
+	}
+	return nil
+}
 
-		for _, fs := range flags {
-			if f.ShowGroupings {
-				// Skip all flags not assigned to this group
-				if fs.Grouping != grp {
-					continue
-				}
-			}
+func (s *stdValue) String() string { return s.v.String() }
 
-			Names := fs.Name[:]
-			if len(Names) > 1 && rlen(Names[0]) > 1 && rlen(Names[1]) == 1 {
-				Names[0], Names[1] = Names[1], Names[0]
-			}
-			line.Reset()
-			for j := 0; j < f.Indent; j++ {
-				line.WriteString(" ")
-			}
-			if haveSingleChar && haveMultiple && rlen(Names[0]) > 1 && len(Names) == 1 {
-				// Indent if we have multiple and single char flags are found
-				line.WriteString("    ")
-			}
-			for i, n := range Names {
-				// Put commas between flags
-				if i > 0 {
-					line.WriteString(", ")
-				}
-				line.WriteString(flagWithMinus(n))
-			}
-			if len(fs.TypeExpected) > 0 {
-				// Put space before type
-				for j := 0; j < f.TypeSpace; j++ {
-					line.WriteString(" ")
-				}
-				line.WriteString(fs.TypeExpected)
-			}
-			// Put space before usage
-			for j := 0; j < f.UsageSpace; j++ {
-				line.WriteString(" ")
-			}
-			usage := fs.Usage
+// -- sync Value, guarding another Value with a mutex
+type syncValue struct {
+	mu sync.Mutex
+	v  Value
+}
 
-			for runewidth.StringWidth(line.String()) < usageIndent {
-				line.WriteString(" ")
-			}
+// SyncValue wraps v so that calls to Set, String and Get are guarded by a
+// mutex, making accumulation into slices safe when Set may be called from
+// several goroutines.  Get returns nil if v is not a Getter.  The wrapped
+// Value is still treated as the kind it is, such as a list emptied by the
+// clear token or a present flag, and its DefaultString is used.
+func SyncValue(v Value) Value {
+	return &syncValue{v: v}
+}
 
-			usage = strings.ReplaceAll(usage, "\n", pad)
-			if _, ok := fs.Value.(*presentValue); ok {
-				fmt.Fprintf(f.Output(), "%s%s\n", line.Bytes(), usage)
-			} else if _, ok := fs.Value.(*stringSliceValue); ok {
-				fmt.Fprintf(f.Output(), "%s%s\n", line.Bytes(), usage)
-			} else if !f.ShowDefaultVal {
-				fmt.Fprintf(f.Output(), "%s%s\n", line.Bytes(), usage)
-			} else if _, ok := fs.Value.(*stringValue); ok {
-				// put quotes on string values
-				format := "%s%s  (%s%q)\n"
-				fmt.Fprintf(f.Output(), format, line.Bytes(), usage, Default, fs.DefValue)
-			} else if _, ok := fs.Value.(flagFuncValue); ok {
-				// put quotes on empty func values
-				format := "%s%s  (%s%q)\n"
-				fmt.Fprintf(f.Output(), format, line.Bytes(), usage, Default, fs.DefValue)
-			} else {
-				format := "%s%s  (%s%s)\n"
-				fmt.Fprintf(f.Output(), format, line.Bytes(), usage, Default, fs.DefValue)
-			}
-		}
+// unwrap returns the Value guarded, to check which kind it is.
+func (s *syncValue) unwrap() Value { return s.v }
 
-		if !f.ShowGroupings {
-			break
+// baseValue returns the Value within any wrappers, such as from SyncValue.
+// Optional methods are called on the wrapper, as it guards them.
+func baseValue(v Value) Value {
+	for {
+		w, ok := v.(interface{ unwrap() Value })
+		if !ok {
+			return v
 		}
+		v = w.unwrap()
 	}
 }
 
-// PrintDefaults prints to standard error the default values of all defined command-line flags.
-func PrintDefaults() {
-	CommandLine.PrintDefaults()
-}
-
-// defaultUsage is the default function to print a usage message.
-func defaultUsage(f *FlagSet) {
-	f.PrintDefaults()
+func (s *syncValue) Set(val []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.v.Set(val)
 }
 
-// NOTE: Usage is not just defaultUsage(CommandLine)
-// because it serves (via godoc flag Usage) as the example
-// for how to write your own usage function.
-
-// Usage prints to standard error a usage message documenting all defined command-line flags.
-// The function is a variable that may be changed to point to a custom function.
-var Usage = func() {
-	if len(CommandLine.Title) > 0 {
-		fmt.Fprintf(CommandLine.Output(), "%s\n\n", CommandLine.Title)
+func (s *syncValue) SetNamed(name string, val []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n, ok := s.v.(NamedSetter); ok {
+		return n.SetNamed(name, val)
 	}
-	post := ""
-	if len(CommandLine.Params) > 0 {
-		post = "[options...] [args...]"
-	} else if len(CommandLine.formal) > 1 {
-		post = "[options...]"
-	} else {
-		post = "[option]"
-	}
-	fmt.Fprintf(CommandLine.Output(), "Usage: %s %s\n", path.Base(os.Args[0]), post)
-	PrintDefaults()
+	return s.v.Set(val)
 }
 
-// Usage prints to standard error a usage message documenting all defined command-line flags.
-// The function is a variable that may be changed to point to a custom function.
-var defaultGroupingHeaders = func(name string, count int) string {
-	plural := ""
-	if count > 1 {
-		plural = "s"
-	}
-	if name == "" {
-		return fmt.Sprintf("Option%s:", plural)
-	} else {
-		return fmt.Sprintf("%s option%s:", name, plural)
+func (s *syncValue) Get() interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if g, ok := s.v.(Getter); ok {
+		return g.Get()
 	}
+	return nil
 }
 
-// NFlag returns the number of flags that have been set.
-func (f *FlagSet) NFlag() int { return len(f.actual) }
+func (s *syncValue) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.v.String()
+}
 
-// NFlag returns the number of command-line flags that have been set.
-func NFlag() int { return len(CommandLine.actual) }
+func (s *syncValue) DefaultString() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if d, ok := s.v.(DefaultStringer); ok {
+		return d.DefaultString()
+	}
+	return s.v.String()
+}
 
-// Arg returns the i'th argument.  Arg(0) is the first remaining argument
-// after flags have been processed.
-func (f *FlagSet) Arg(i int) string {
-	if i < 0 || i >= len(f.args) {
-		return ""
+func (s *syncValue) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, ok := s.v.(clearable); ok {
+		c.Clear()
 	}
-	return f.args[i]
 }
 
-// Arg returns the i'th command-line argument.  Arg(0) is the first remaining argument
-// after flags have been processed.
-func Arg(i int) string {
-	return CommandLine.Arg(i)
+func (s *syncValue) Accepts(v string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.v.(optionalArg)
+	return ok && o.Accepts(v)
 }
 
-// NArg is the number of arguments remaining after flags have been processed.
-func (f *FlagSet) NArg() int { return len(f.args) }
+func (s *syncValue) IsPresentFlag() bool {
+	p, ok := s.v.(presentFlag)
+	return ok && p.IsPresentFlag()
+}
 
-// NArg is the number of arguments remaining after flags have been processed.
-func NArg() int { return len(CommandLine.args) }
+func (s *syncValue) IsIndexed() bool {
+	i, ok := s.v.(indexedFlag)
+	return ok && i.IsIndexed()
+}
 
-// Args returns the non-flag arguments.
-func (f *FlagSet) Args() []string { return f.args }
+// Value is the interface to the dynamic value stored in a flag.
+// (The default value is represented as a string.)
+type Value interface {
+	String() string
+	Set([]string) error
+}
 
-// Args returns the non-flag command-line arguments.
-func Args() []string { return CommandLine.args }
+// Getter is an interface that allows the contents of a Value to be retrieved.
+// It wraps the Value interface, rather than being part of it, because it
+// appeared after Go 1 and its compatibility rules. All Value types provided
+// by this package satisfy the Getter interface.
+type Getter interface {
+	Value
+	Get() interface{}
+}
 
-// PresVar defines a present flag with specified name and usage string.
-// The return value is the address of a bool variable that stores true if seen.
-func (f *FlagSet) PresVar(p *bool, name string, usage string) {
-	f.Var(newPresentValue(p), name, usage, "", 0)
+// DefaultStringer is an interface for Values which show their default in the
+// help differently to String, such as "none" rather than "0s".  When a Value
+// implements it, DefaultString is called in place of String for DefValue when
+// the flag is defined.
+type DefaultStringer interface {
+	DefaultString() string
 }
 
-// PresVar defines a present flag with specified name and usage string.
-// The return value is the address of a bool variable that stores true if seen.
-func PresVar(p *bool, name string, usage string) {
-	CommandLine.Var(newPresentValue(p), name, usage, "", 0)
+// NamedSetter is an interface for Values which need to know the name the flag
+// was given by, such as one Value defined with the names "increase" and
+// "decrease".  When a Value implements it, SetNamed is called in place of Set
+// with the name matched, without any dashes.
+type NamedSetter interface {
+	Value
+	SetNamed(name string, vals []string) error
 }
 
-// PresVar defines a present flag with specified name and usage string.
-// The return value is the address of a bool variable that stores true if seen.
-func (f *FlagSet) Pres(name string, usage string) *bool {
-	p := new(bool)
-	f.PresVar(p, name, usage)
-	return p
+// ErrorHandling defines how to handle flag parsing errors.
+type ErrorHandling int
+
+const (
+	ContinueOnError ErrorHandling = iota
+	ExitOnError
+	PanicOnError
+)
+
+// A FlagSet represents a set of defined flags.
+type FlagSet struct {
+	// Usage is the function called when an error occurs while parsing flags.
+	// The field is a function (not a method) that may be changed to point to
+	// a custom error handler.
+	Usage func()
+
+	name             string
+	Title            string
+	parsed           bool
+	actual           []*Flag
+	formal           []*Flag
+	nameList         []string
+	Params           []Param // argument parsers for after flags
+	args             []string
+	rawArgs          []string // copy of the arguments given to Parse
+	procArgs         []string // arguments being processed (gnu only)
+	procFlag         string   // flag being processed (gnu only)
+	procAttached     bool     // procFlag followed "=", so is a value even if empty
+	allowIntersperse bool     // (gnu only)
+	alignDefaults    bool     // line up the default annotations in a column
+	nameSeparator    string   // placed between the names of a flag in help
+	boolTrue         string   // shown in help for a true bool default
+	boolFalse        string   // shown in help for a false bool default
+	hideFalseBool    bool     // show no default for a false bool
+	helpRequested    bool     // the built-in help was invoked
+	noBuiltinHelp    bool     // do not handle an undefined -h or --help
+	dumpFlags        bool     // --dump-flags was seen
+	stopAtOperand    bool     // stop parsing at the first non-flag argument
+	envPrefix        string   // prefix for deriving environment variable names
+	clearToken       string   // value which empties a slice flag
+	clearTokenSet    bool     // clearToken is in use, as it may be empty
+	sliceMutation    bool     // a leading = replaces a slice flag, + adds to it
+	valueIndirection bool     // resolve @env:NAME and @file:PATH values
+	echoArgsOnError  bool     // print the command line before parse errors
+	noPositional     bool     // fail on any non-flag argument
+	recordOrder      bool     // keep each flag seen, in order, in ordered
+	singleDashLong   bool     // look up -name as a long name before a cluster
+	frozen           bool     // no more flags may be defined
+	argLimit         bool     // maxArgs is set
+	maxArgs          int      // most non-flag arguments allowed while parsing
+	description      string   // shown after the usage line
+	epilogue         string   // shown at the end of the usage
+	argBounds        bool     // the number of arguments is checked
+	argMin           int      // minimum number of arguments
+	argMax           int      // maximum number of arguments, -1 for no limit
+	exitOnError      bool     // does the program exit if there's an error?
+	errorHandling    ErrorHandling
+	output           io.Writer // nil means stderr; use out() accessor
+	errOutput        io.Writer // nil means output; use ErrorOutput() accessor
+	curGrouping      string
+	mulock           *sync.Mutex
+	onFlag           func(name string, value []string) // called as each flag is set
+	defaultsFrom     []defaultFrom                     // defaults taken from other flags
+	passthrough      *[]string                         // arguments after the "--" terminator, if bound
+	implies          []implied                         // flags set when another flag is seen
+	exactlyOne       [][]string                        // groups of flags of which one must be set
+	requires         [][]string                        // flags, each followed by those it needs
+	aliases          map[string]alias                  // deprecated names of flags
+	expansions       map[string][]string               // names which expand to other arguments
+	ordered          []Occurrence                      // flags seen, in command line order
+	middleware       []func(*ParsedFlag) error         // called with each flag before it is set
+	argsFilter       func([]string) ([]string, error)  // applied to the arguments after parsing
+	lazy             []func(*FlagSet)                  // define flags when parsing starts
+	examples         []example                         // shown after the flags in the usage
+	defaultTemplates map[*Flag]defaultTemplate         // defaults shown from templates
+	namePolicy       func(name string) error           // checks the names of flags as defined
+	procNext         func() (string, bool)             // pulls more arguments, see ParseFunc
+	occurrences      map[*Flag]int                     // times each flag was given since Parse
+
+	// SetUsageIndent tells the DefaultPrinter how many spaces to add to before
+	// printing the usage for each flag.  By default this is 0 and determined by
+	// the maximum comma seperated name length.
+	Indent      int // beginning of line space
+	UsageIndent int // usage column position
+
+	UsageSpace int // minimum number of spaces required before usage
+	TypeSpace  int // minimum number of spaces required before input type string
+	Width      int // wrap usage text to fit in this many columns, 0 means no wrapping
+
+	ShowGroupings   bool                     // Show the flags in groups
+	GroupingHeaders func(string, int) string // function used to generate headers, like "Options:"
+
+	ShowDefaultVal bool // Display the (Default: "") example
+
+	// FlagKnownAs allows different projects to customise what their flags are
+	// known as, e.g. 'flag', 'option', 'item'. All error/log messages
+	// will use that name when referring to an individual items/flags in this set.
+	// For example, if this value is 'option', the default message 'value for param'
+	// will become 'value for option'.
+	// Default value is 'flag'.
+	FlagKnownAs string
 }
 
-// Pres defines a present flag with specified name and usage string.
-// The return value is the address of a bool variable that stores true if seen.
-func Pres(name string, usage string) *bool {
-	return CommandLine.Pres(name, usage)
+// An Occurrence is a flag as it was seen on the command line, with the name
+// used and the values given.
+type Occurrence struct {
+	Name  string
+	Value []string
 }
 
-// BoolVar defines a bool flag with specified name, default value, and usage string.
-// The argument p points to a bool variable in which to store the value of the flag.
-func (f *FlagSet) BoolVar(p *bool, name string, value bool, usage string, typeExp string) {
-	f.Var(newBoolValue(value, p), name, usage, typeExp, 1)
+// A ParsedFlag is a flag seen while parsing, as handed to middleware, see Use.
+type ParsedFlag struct {
+	Flag   *Flag    // the flag being set
+	Name   string   // the name the flag was given by
+	Tokens []string // the values given, which may be changed by middleware
+	Index  int      // occurrence of the flag since Parse, counting from 0
 }
 
-// BoolVar defines a bool flag with specified name, default value, and usage string.
-// The argument p points to a bool variable in which to store the value of the flag.
-func BoolVar(p *bool, name string, value bool, usage string, typeExp string) {
-	CommandLine.Var(newBoolValue(value, p), name, usage, typeExp, 1)
+// defaultTemplate records a template for showing the default of a flag, and
+// the data to execute it with.
+type defaultTemplate struct {
+	tmpl *template.Template
+	data interface{}
 }
 
-// Bool defines a bool flag with specified name, default value, and usage string.
-// The return value is the address of a bool variable that stores the value of the flag.
-func (f *FlagSet) Bool(name string, value bool, usage string, typeExp string) *bool {
-	p := new(bool)
-	f.BoolVar(p, name, value, usage, typeExp)
-	return p
+// example records a usage example, added with AddExample.
+type example struct {
+	description string
+	commandLine string
 }
 
-// Bool defines a bool flag with specified name, default value, and usage string.
-// The return value is the address of a bool variable that stores the value of the flag.
-func Bool(name string, value bool, usage string, typeExp string) *bool {
-	return CommandLine.Bool(name, value, usage, typeExp)
+// defaultFrom records a flag whose default is the final value of another flag.
+type defaultFrom struct {
+	name   string
+	source string
 }
 
-// IntVar defines an int flag with specified name, default value, and usage string.
-// The argument p points to an int variable in which to store the value of the flag.
-func (f *FlagSet) IntVar(p *int, name string, value int, usage string, typeExp string) {
-	f.Var(newIntValue(value, p), name, usage, typeExp, 1)
+// alias records a deprecated name for a flag, and the message to warn with.
+type alias struct {
+	flag      *Flag
+	canonical string
+	message   string
 }
 
-// IntVar defines an int flag with specified name, default value, and usage string.
-// The argument p points to an int variable in which to store the value of the flag.
-func IntVar(p *int, name string, value int, usage string, typeExp string) {
-	CommandLine.Var(newIntValue(value, p), name, usage, typeExp, 1)
+// implied records the flags set, and their values, when a flag is seen.
+type implied struct {
+	name string
+	sets map[string][]string
 }
 
-// Int defines an int flag with specified name, default value, and usage string.
-// The return value is the address of an int variable that stores the value of the flag.
-func (f *FlagSet) Int(name string, value int, usage string, typeExp string) *int {
-	p := new(int)
-	f.IntVar(p, name, value, usage, typeExp)
-	return p
+// A Flag represents the state of a flag.
+type Flag struct {
+	Name         []string                      // name as it appears on command line
+	Usage        string                        // help message
+	Value        Value                         // value as set
+	DefValue     string                        // default value
+	TypeExpected string                        // helpful hint on what is expected
+	ArgsNeeded   int                           // arg count wanted
+	Grouping     string                        // organize flags into groups
+	Options      func(string, string) []string // function to return possible outcomes for bash completion
+	UTF8         bool                          // values must be valid UTF-8
+	Env          string                        // environment variable to use when not set
+	Required     bool                          // must be set, else Parse fails
+	Hidden       bool                          // left out of the help
+	Annotations  map[string]string             // metadata for external tooling
+	Pattern      *regexp.Regexp                // values must match, if set
+	NonNegative  bool                          // numeric values must be zero or more
+	TrimSpace    bool                          // white space is trimmed from values
+	Experimental bool                          // only usable along with --experimental
 }
 
-// Int defines an int flag with specified name, default value, and usage string.
-// The return value is the address of an int variable that stores the value of the flag.
-func Int(name string, value int, usage string, typeExp string) *int {
-	return CommandLine.Int(name, value, usage, typeExp)
+type Param struct {
+	Usage        string                                                  // help message
+	Value        Value                                                   // value as set
+	DefValue     string                                                  // default value
+	TypeExpected string                                                  // helpful hint on what is expected
+	Options      []string                                                // Available options for tab-fill
+	OptionsFunc  func(flagsSeen []Flag, argsSeen []string) []string      // Get options for bash completion
+	Test         func(flagsSeen []Flag, argsSeen []string) (bool, error) // Options
 }
 
-// Int64Var defines an int64 flag with specified name, default value, and usage string.
-// The argument p points to an int64 variable in which to store the value of the flag.
-func (f *FlagSet) Int64Var(p *int64, name string, value int64, usage string, typeExp string) {
-	f.Var(newInt64Value(value, p), name, usage, typeExp, 1)
+// set trims and validates the values against the constraints on the flag and
+// then hands them to the Value, along with the name used if it is a
+// NamedSetter.
+func (flag *Flag) set(name string, vals []string) error {
+	if flag.TrimSpace {
+		trimmed := make([]string, len(vals))
+		for i, v := range vals {
+			trimmed[i] = strings.TrimSpace(v)
+		}
+		vals = trimmed
+	}
+	if flag.UTF8 {
+		for _, v := range vals {
+			if !utf8.ValidString(v) {
+				return errors.New("not valid UTF-8")
+			}
+		}
+	}
+	if flag.Pattern != nil {
+		for _, v := range vals {
+			if !flag.Pattern.MatchString(v) {
+				return fmt.Errorf("does not match the pattern %s", flag.Pattern)
+			}
+		}
+	}
+	var restore func()
+	if flag.NonNegative {
+		restore = saveNumber(flag.Value)
+	}
+	var err error
+	if n, ok := flag.Value.(NamedSetter); ok {
+		err = n.SetNamed(name, vals)
+	} else {
+		err = flag.Value.Set(vals)
+	}
+	if neg, _ := isNegative(flag.Value); err == nil && flag.NonNegative && neg {
+		// put back the value from before, as for values out of range
+		if restore != nil {
+			restore()
+		}
+		return errors.New("must not be negative")
+	}
+	return err
 }
 
-// Int64Var defines an int64 flag with specified name, default value, and usage string.
-// The argument p points to an int64 variable in which to store the value of the flag.
-func Int64Var(p *int64, name string, value int64, usage string, typeExp string) {
-	CommandLine.Var(newInt64Value(value, p), name, usage, typeExp, 1)
+// isNegative reports whether the value of a numeric or duration Value is less
+// than zero.
+func isNegative(v Value) (negative, ok bool) {
+	g, ok := v.(Getter)
+	if !ok {
+		return false, false
+	}
+	switch x := g.Get().(type) {
+	case int:
+		return x < 0, true
+	case int64:
+		return x < 0, true
+	case float64:
+		return x < 0, true
+	case time.Duration:
+		return x < 0, true
+	}
+	return false, false
 }
 
-// Int64 defines an int64 flag with specified name, default value, and usage string.
-// The return value is the address of an int64 variable that stores the value of the flag.
-func (f *FlagSet) Int64(name string, value int64, usage string, typeExp string) *int64 {
-	p := new(int64)
-	f.Int64Var(p, name, value, usage, typeExp)
-	return p
+// saveNumber returns a func which puts back the current value of a numeric or
+// duration Value of this package, or nil for other Values.
+func saveNumber(v Value) (restore func()) {
+	if s, ok := v.(*syncValue); ok {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if r := saveNumber(s.v); r != nil {
+			return func() {
+				s.mu.Lock()
+				defer s.mu.Unlock()
+				r()
+			}
+		}
+		return nil
+	}
+	var p interface{} // the variable holding the value
+	switch x := v.(type) {
+	case *intValue:
+		p = (*int)(x)
+	case *countValue:
+		p = (*int)(x)
+	case *logLevelValue:
+		p = (*int)(x)
+	case *optionalIntValue:
+		p = x.p
+	case *intRangeValue:
+		p = (*int)(x.intValue)
+	case *int64Value:
+		p = (*int64)(x)
+	case *int64RangeValue:
+		p = (*int64)(x.int64Value)
+	case *float64Value:
+		p = (*float64)(x)
+	case *unitFloatValue:
+		p = x.p
+	case *durationValue:
+		p = (*time.Duration)(x)
+	case *zeroDurationValue:
+		p = (*time.Duration)(x.durationValue)
+	case *unitDurationValue:
+		p = (*time.Duration)(x.durationValue)
+	case *keywordDurationValue:
+		p = (*time.Duration)(x.durationValue)
+	case *isoDurationValue:
+		p = (*time.Duration)(x)
+	}
+	switch p := p.(type) {
+	case *int:
+		old := *p
+		return func() { *p = old }
+	case *int64:
+		old := *p
+		return func() { *p = old }
+	case *float64:
+		old := *p
+		return func() { *p = old }
+	case *time.Duration:
+		old := *p
+		return func() { *p = old }
+	}
+	return nil
 }
 
-// Int64 defines an int64 flag with specified name, default value, and usage string.
-// The return value is the address of an int64 variable that stores the value of the flag.
-func Int64(name string, value int64, usage string, typeExp string) *int64 {
-	return CommandLine.Int64(name, value, usage, typeExp)
+// splitOn, reads out a string and returns a slice
+func splitOn(str string, c rune, count int) (out []string) {
+	var line bytes.Buffer
+	for i := 0; i < len(str); {
+		r, size := utf8.DecodeRuneInString(str[i:])
+		if r == c {
+			if line.Len() == 0 {
+				// skip empty fields
+				i += size
+				continue
+			}
+			out = append(out, line.String())
+			line.Reset()
+			if len(out) == count-1 {
+				line.WriteString(str[i+size:])
+				break
+			}
+		} else {
+			line.WriteRune(r)
+		}
+		i += size
+	}
+	if line.Len() > 0 {
+		out = append(out, line.String())
+	}
+	return
 }
 
-// UintVar defines a uint flag with specified name, default value, and usage string.
-// The argument p points to a uint variable in which to store the value of the flag.
-func (f *FlagSet) UintVar(p *uint, name string, value uint, usage string, typeExp string) {
-	f.Var(newUintValue(value, p), name, usage, typeExp, 1)
+// sortFlags returns the flags as a slice in lexicographical sorted order.
+func sortFlags(flags []*Flag) []*Flag {
+	list := make(flagsByName, len(flags))
+	copy(list, flags)
+	//i := 0
+	//for _, f := range flags {
+	//	list[i] = f.Name[0]
+	//	i++
+	//}
+	sort.Sort(list)
+	//result := make([]*Flag, len(list))
+	//for i, name := range list {
+	//	result[i] = flags[name]
+	//}
+	return list
 }
 
-// UintVar defines a uint flag with specified name, default value, and usage string.
-// The argument p points to a uint  variable in which to store the value of the flag.
-func UintVar(p *uint, name string, value uint, usage string, typeExp string) {
-	CommandLine.Var(newUintValue(value, p), name, usage, typeExp, 1)
+// Output returns the destination for usage and error messages. os.Stderr is returned if
+// output was not set or was set to nil.
+func (f *FlagSet) Output() io.Writer {
+	if f.output == nil {
+		return os.Stderr
+	}
+	return f.output
 }
 
-// Uint defines a uint flag with specified name, default value, and usage string.
-// The return value is the address of a uint  variable that stores the value of the flag.
-func (f *FlagSet) Uint(name string, value uint, usage string, typeExp string) *uint {
-	p := new(uint)
-	f.UintVar(p, name, value, usage, typeExp)
-	return p
+// ErrorOutput returns the destination for error messages and warnings.
+// Output is returned if the error output was not set or was set to nil.
+func (f *FlagSet) ErrorOutput() io.Writer {
+	if f.errOutput == nil {
+		return f.Output()
+	}
+	return f.errOutput
 }
 
-// Uint defines a uint flag with specified name, default value, and usage string.
-// The return value is the address of a uint  variable that stores the value of the flag.
-func Uint(name string, value uint, usage string, typeExp string) *uint {
-	return CommandLine.Uint(name, value, usage, typeExp)
+// Name returns the name of the flag set.
+func (f *FlagSet) Name() string {
+	return f.name
 }
 
-// Uint64Var defines a uint64 flag with specified name, default value, and usage string.
-// The argument p points to a uint64 variable in which to store the value of the flag.
-func (f *FlagSet) Uint64Var(p *uint64, name string, value uint64, usage string, typeExp string) {
-	f.Var(newUint64Value(value, p), name, usage, typeExp, 1)
+// ErrorHandling returns the error handling behavior of the flag set.
+func (f *FlagSet) ErrorHandling() ErrorHandling {
+	return f.errorHandling
 }
 
-// Uint64Var defines a uint64 flag with specified name, default value, and usage string.
-// The argument p points to a uint64 variable in which to store the value of the flag.
-func Uint64Var(p *uint64, name string, value uint64, usage string, typeExp string) {
-	CommandLine.Var(newUint64Value(value, p), name, usage, typeExp, 1)
+// SetOutput sets the destination for usage and error messages.
+// If output is nil, os.Stderr is used.
+func (f *FlagSet) SetOutput(output io.Writer) {
+	f.output = output
 }
 
-// Uint64 defines a uint64 flag with specified name, default value, and usage string.
-// The return value is the address of a uint64 variable that stores the value of the flag.
-func (f *FlagSet) Uint64(name string, value uint64, usage string, typeExp string) *uint64 {
-	p := new(uint64)
-	f.Uint64Var(p, name, value, usage, typeExp)
-	return p
+// SetErrorOutput sets the destination for error messages and warnings, such
+// as stderr while the usage goes to stdout.  If output is nil, Output is used.
+func (f *FlagSet) SetErrorOutput(output io.Writer) {
+	f.errOutput = output
+}
+
+// GroupingSet creates a grouping set for new flags added.  This is helpful if
+// there are many flags and they can be organized in smaller groupings.
+func GroupingSet(grouping string) {
+	CommandLine.curGrouping = grouping
+}
+
+// GroupingSet creates a grouping set for new flags added.  This is helpful if
+// there are many flags and they can be organized in smaller groupings.
+func (f *FlagSet) GroupingSet(grouping string) {
+	f.curGrouping = grouping
+}
+
+// SetNameSeparator sets the separator PrintDefaults places between the names
+// of a flag with more than one name, such as "/" or "|".  The default is ", ".
+func (f *FlagSet) SetNameSeparator(sep string) {
+	f.nameSeparator = sep
+}
+
+// SetNameSeparator sets the separator PrintDefaults places between the names
+// of a command-line flag with more than one name.
+func SetNameSeparator(sep string) {
+	CommandLine.nameSeparator = sep
+}
+
+// nameSep returns the separator to place between the names of a flag.
+func (f *FlagSet) nameSep() string {
+	if f.nameSeparator == "" {
+		return ", "
+	}
+	return f.nameSeparator
+}
+
+// SetBoolDisplay sets the words PrintDefaults shows for the default of a bool
+// flag, such as "on" and "off" for (Default: off).  This affects only the
+// help, the values accepted when parsing are unchanged.  Empty words restore
+// the default of true and false.
+func (f *FlagSet) SetBoolDisplay(trueWord, falseWord string) {
+	f.boolTrue = trueWord
+	f.boolFalse = falseWord
+}
+
+// SetBoolDisplay sets the words PrintDefaults shows for the default of a bool
+// command-line flag, see FlagSet.SetBoolDisplay.
+func SetBoolDisplay(trueWord, falseWord string) {
+	CommandLine.boolTrue = trueWord
+	CommandLine.boolFalse = falseWord
+}
+
+// SetHideFalseBoolDefaults tells PrintDefaults to show no default for bool
+// flags which default to false, as for present flags, which cuts the noise
+// of many (Default: false) annotations.  Bool flags defaulting to true still
+// show their default.
+func (f *FlagSet) SetHideFalseBoolDefaults(hide bool) {
+	f.hideFalseBool = hide
+}
+
+// SetHideFalseBoolDefaults tells PrintDefaults to show no default for bool
+// command-line flags which default to false.
+func SetHideFalseBoolDefaults(hide bool) {
+	CommandLine.hideFalseBool = hide
+}
+
+// boolDisplay returns the word to show in help for the bool default def.
+func (f *FlagSet) boolDisplay(def string) string {
+	switch {
+	case def == "true" && f.boolTrue != "":
+		return f.boolTrue
+	case def == "false" && f.boolFalse != "":
+		return f.boolFalse
+	}
+	return def
+}
+
+// SetAlignDefaults tells PrintDefaults to line up the (Default: x) annotations
+// in a column after the longest usage, rather than directly after each usage.
+func (f *FlagSet) SetAlignDefaults(alignDefaults bool) {
+	f.alignDefaults = alignDefaults
+}
+
+// SetAlignDefaults tells PrintDefaults to line up the (Default: x) annotations
+// of the command-line flags in a column after the longest usage.
+func SetAlignDefaults(alignDefaults bool) {
+	CommandLine.alignDefaults = alignDefaults
+}
+
+// SetAllowIntersperse tells the parser if flags can be interspersed with other
+// arguments.  If AllowIntersperse is set to true, arguments and flags can be
+// interspersed, that is flags can follow positional arguments.
+//
+// Example of true:
+//   prog -flag1 input1 input2 -flag2
+// Example of false: (default)
+//   prog -flag1 -flag2 input1 input2
+func (f *FlagSet) SetAllowIntersperse(allowIntersperse bool) {
+	f.allowIntersperse = allowIntersperse
+}
+
+// SetAllowIntersperse tells the parser if flags can be interspersed with other
+// arguments.  If AllowIntersperse is set to true, arguments and flags can be
+// interspersed, that is flags can follow positional arguments.
+//
+// Example of true:
+//   prog -flag1 input1 input2 -flag2
+// Example of false: (default)
+//   prog -flag1 -flag2 input1 input2
+func SetAllowIntersperse(allowIntersperse bool) {
+	CommandLine.allowIntersperse = allowIntersperse
+}
+
+// SetOnFlag sets a callback which is called each time a flag is successfully
+// set while parsing, with the name as it was given on the command line and the
+// values handed to the flag.  This is useful for logging or progress feedback.
+func (f *FlagSet) SetOnFlag(fn func(name string, value []string)) {
+	f.onFlag = fn
+}
+
+// SetOnFlag sets a callback which is called each time a command-line flag is
+// successfully set while parsing, with the name as it was given on the command
+// line and the values handed to the flag.
+func SetOnFlag(fn func(name string, value []string)) {
+	CommandLine.onFlag = fn
+}
+
+// SetDefaultFromFlag declares that the flag name takes its default from the
+// flag sourceName.  The source is evaluated after all the arguments have been
+// parsed, so its final value is used, and only if name was not set itself.
+// The source may take its own default from another flag, which is resolved
+// first, but SetDefaultFromFlag panics if the chain leads back to name.  The
+// default is checked as a value given on the command line would be.
+//
+// Example:
+//   prog --input-dir /data            // --output-dir is /data
+//   prog --input-dir /data --output-dir /tmp
+func (f *FlagSet) SetDefaultFromFlag(name, sourceName string) {
+	if f.defaultReaches(sourceName, name) {
+		fmt.Fprintf(f.ErrorOutput(), "%s %v default taken from itself: %s\n", f.name, f.FlagKnownAs, name)
+		panic(fmt.Sprintf("%v default cycle", f.FlagKnownAs))
+	}
+	f.defaultsFrom = append(f.defaultsFrom, defaultFrom{name: name, source: sourceName})
+}
+
+// defaultReaches reports whether the flag from is, or takes its default
+// through SetDefaultFromFlag from, the flag to.
+func (f *FlagSet) defaultReaches(from, to string) bool {
+	if f.sameFlag(from, to) {
+		return true
+	}
+	for _, d := range f.defaultsFrom {
+		if f.sameFlag(d.name, from) && f.defaultReaches(d.source, to) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameFlag reports whether the names a and b are the same flag, either as
+// given or once looked up.
+func (f *FlagSet) sameFlag(a, b string) bool {
+	if a == b {
+		return true
+	}
+	flag := f.Lookup(a)
+	return flag != nil && flag == f.Lookup(b)
+}
+
+// SetDefaultFromFlag declares that the command-line flag name takes its
+// default from the flag sourceName, evaluated after all the arguments have
+// been parsed.
+func SetDefaultFromFlag(name, sourceName string) {
+	CommandLine.SetDefaultFromFlag(name, sourceName)
+}
+
+// SetBuiltinHelp tells the parser if an undefined --help or -h should print the
+// usage and return ErrHelp, which it does by default.  When false, they are
+// treated as any other flag which has not been defined.
+func (f *FlagSet) SetBuiltinHelp(builtinHelp bool) {
+	f.noBuiltinHelp = !builtinHelp
+}
+
+// SetBuiltinHelp tells the parser if an undefined --help or -h should print the
+// usage and return ErrHelp, which it does by default.
+func SetBuiltinHelp(builtinHelp bool) {
+	CommandLine.noBuiltinHelp = !builtinHelp
+}
+
+// Freeze stops any more flags, aliases or expansions being defined in the set,
+// so defining one panics, to catch late definitions such as from plugins.
+// Functions added with AddLazy may still define flags when Parse starts.
+func (f *FlagSet) Freeze() {
+	f.frozen = true
+}
+
+// Freeze stops any more flags being defined in the command-line flag set.
+func Freeze() {
+	CommandLine.Freeze()
+}
+
+// checkFrozen panics if the set is frozen.
+func (f *FlagSet) checkFrozen() {
+	if f.frozen {
+		fmt.Fprintf(f.ErrorOutput(), "%s %v set is frozen\n", f.name, f.FlagKnownAs)
+		panic(fmt.Sprintf("%v set is frozen", f.FlagKnownAs))
+	}
+}
+
+// DeprecatedAlias defines oldName as another name for the existing flag
+// canonicalName, for renaming flags across releases.  Using oldName prints a
+// warning with message to Output, while canonicalName is silent.  Deprecated
+// aliases are not shown in the help.
+func (f *FlagSet) DeprecatedAlias(oldName, canonicalName, message string) {
+	flag := f.mustLookup(canonicalName)
+	f.checkFrozen()
+	if f.Lookup(oldName) != nil {
+		fmt.Fprintf(f.ErrorOutput(), "%s %v redefined: %s\n", f.name, f.FlagKnownAs, oldName)
+		panic(fmt.Sprintf("%v redefinition", f.FlagKnownAs)) // Happens only if flags are declared with identical names
+	}
+	if f.aliases == nil {
+		f.aliases = make(map[string]alias)
+	}
+	f.aliases[oldName] = alias{flag: flag, canonical: canonicalName, message: message}
+}
+
+// DeprecatedAlias defines oldName as another name for the existing command-line
+// flag canonicalName, which prints a warning with message when used.
+func DeprecatedAlias(oldName, canonicalName, message string) {
+	CommandLine.DeprecatedAlias(oldName, canonicalName, message)
+}
+
+// DefineExpansion defines name as a macro which, when parsed, is replaced by
+// the arguments in expandsTo as if the user had typed them in its place.  The
+// name is given with "--" for a long name, or with a single "-" to match the
+// whole argument, such as "-O2".  An expansion may use other expansions but
+// must not refer back to itself.
+//
+// Example:
+//   fs.DefineExpansion("O2", []string{"--inline", "--unroll", "--level", "2"})
+//   prog -O2 file   // same as: prog --inline --unroll --level 2 file
+func (f *FlagSet) DefineExpansion(name string, expandsTo []string) {
+	f.checkFrozen()
+	if f.Lookup(name) != nil || f.expansions[name] != nil {
+		fmt.Fprintf(f.ErrorOutput(), "%s %v redefined: %s\n", f.name, f.FlagKnownAs, name)
+		panic(fmt.Sprintf("%v redefinition", f.FlagKnownAs)) // Happens only if flags are declared with identical names
+	}
+	if f.expansions == nil {
+		f.expansions = make(map[string][]string)
+	}
+	f.expansions[name] = append([]string{}, expandsTo...)
+}
+
+// DefineExpansion defines name as a macro which, when parsed, is replaced by
+// the command-line arguments in expandsTo, see FlagSet.DefineExpansion.
+func DefineExpansion(name string, expandsTo []string) {
+	CommandLine.DefineExpansion(name, expandsTo)
+}
+
+// warnDeprecated prints the warning for a deprecated alias having been used.
+func (f *FlagSet) warnDeprecated(name string, a alias) {
+	msg := fmt.Sprintf("%v %s is deprecated, use %s instead", f.FlagKnownAs,
+		flagWithMinus(name), flagWithMinus(a.canonical))
+	if a.message != "" {
+		msg += ": " + a.message
+	}
+	fmt.Fprintln(f.ErrorOutput(), msg)
+}
+
+// MarkExactlyOne declares that exactly one of the named flags must be set,
+// such as for choosing a mode with --quiet, --verbose or --normal, else Parse
+// fails.  This is stricter than the flags being mutually exclusive, as giving
+// none of them is also an error.
+func (f *FlagSet) MarkExactlyOne(names ...string) {
+	for _, name := range names {
+		f.mustLookup(name)
+	}
+	f.exactlyOne = append(f.exactlyOne, names)
+}
+
+// MarkExactlyOne declares that exactly one of the named command-line flags
+// must be set.
+func MarkExactlyOne(names ...string) {
+	CommandLine.MarkExactlyOne(names...)
+}
+
+// MarkRequires declares that when the flag name is set, the flags in needs
+// must be set too, such as --tls-key for --tls-cert, else Parse fails listing
+// every dependency not met.
+func (f *FlagSet) MarkRequires(name string, needs ...string) {
+	f.mustLookup(name)
+	for _, need := range needs {
+		f.mustLookup(need)
+	}
+	f.requires = append(f.requires, append([]string{name}, needs...))
+}
+
+// MarkRequires declares that when the command-line flag name is set, the
+// flags in needs must be set too.
+func MarkRequires(name string, needs ...string) {
+	CommandLine.MarkRequires(name, needs...)
+}
+
+// checkRequires ensures the flags needed by each flag set, as declared with
+// MarkRequires, are set too.
+func (f *FlagSet) checkRequires() error {
+	var unmet, names []string
+	seen := make(map[string]bool)
+	for _, req := range f.requires {
+		if !f.isSet(f.Lookup(req[0])) {
+			continue
+		}
+		var missing []string
+		for _, need := range req[1:] {
+			if !f.isSet(f.Lookup(need)) {
+				missing = append(missing, flagWithMinus(need))
+				if !seen[need] {
+					seen[need] = true
+					names = append(names, need)
+				}
+			}
+		}
+		if len(missing) > 0 {
+			unmet = append(unmet, fmt.Sprintf("%s requires %s", flagWithMinus(req[0]), strings.Join(missing, ", ")))
+		}
+	}
+	if len(unmet) > 0 {
+		return f.failFlag(&ParseError{Name: names[0], Names: names, Reason: ReasonRequires},
+			"%s", strings.Join(unmet, "; "))
+	}
+	return nil
+}
+
+// checkExactlyOne ensures one flag in each group marked with MarkExactlyOne is
+// set.
+func (f *FlagSet) checkExactlyOne() error {
+	for _, group := range f.exactlyOne {
+		var set int
+		var names []string
+		for _, name := range group {
+			if f.isSet(f.Lookup(name)) {
+				set++
+			}
+			names = append(names, flagWithMinus(name))
+		}
+		if set != 1 {
+			return f.failFlag(&ParseError{Name: group[0], Names: group, Reason: ReasonExactlyOne},
+				"exactly one of %s is required", strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
+
+// MarkImplies declares that when the flag name is seen, the flags in sets are
+// also set to the values given, as if they were on the command line.  Values
+// given explicitly on the command line win over implied ones, whatever the
+// order of the arguments.
+//
+// Example:
+//   fs.MarkImplies("debug", map[string][]string{
+//     "verbose":   {},
+//     "log-level": {"debug"},
+//   })
+func (f *FlagSet) MarkImplies(name string, sets map[string][]string) {
+	f.mustLookup(name)
+	for target := range sets {
+		f.mustLookup(target)
+	}
+	f.implies = append(f.implies, implied{name: name, sets: sets})
+}
+
+// MarkImplies declares that when the command-line flag name is seen, the flags
+// in sets are also set to the values given, unless set explicitly.
+func MarkImplies(name string, sets map[string][]string) {
+	CommandLine.MarkImplies(name, sets)
+}
+
+// EnableDumpFlags defines the --dump-flags flag.  When it is seen, Parse
+// prints the names and values of all the flags to Output once the arguments
+// have been parsed, and returns ErrDumpFlags.  This is helpful for users
+// checking what their command line is doing.
+func (f *FlagSet) EnableDumpFlags() {
+	f.Var(newPresentValue(&f.dumpFlags), "dump-flags", "print the values of all flags and exit", "", 0)
+}
+
+// EnableDumpFlags defines the --dump-flags command-line flag, which prints the
+// names and values of all the flags once parsed and exits.
+func EnableDumpFlags() {
+	CommandLine.EnableDumpFlags()
+}
+
+// SetEnv sets the environment variable the named flag takes its value from
+// when it is not given on the command line.  This overrides the name derived
+// from SetEnvPrefix.
+func (f *FlagSet) SetEnv(name, env string) {
+	f.mustLookup(name).Env = env
+}
+
+// SetEnv sets the environment variable the named command-line flag takes its
+// value from when it is not given on the command line.
+func SetEnv(name, env string) {
+	CommandLine.SetEnv(name, env)
+}
+
+// SetEnvPrefix lets every flag take its value from the environment when it is
+// not given on the command line.  The variable name is the prefix and the
+// flag name joined with an underscore, uppercased, with dashes replaced by
+// underscores; a flag named "max-conn" with the prefix "app" reads APP_MAX_CONN.
+// An empty prefix turns this off again.
+func (f *FlagSet) SetEnvPrefix(prefix string) {
+	f.envPrefix = prefix
+}
+
+// SetEnvPrefix lets every command-line flag take its value from the
+// environment when it is not given on the command line.
+func SetEnvPrefix(prefix string) {
+	CommandLine.envPrefix = prefix
+}
+
+// SetClearToken sets a value which empties a flag collecting a list of values,
+// such as a StringSlice, rather than being added to it.  Any values following
+// the token are then added as usual.  This lets a command line override a list
+// given earlier, such as from a config file, with an empty list.  The token
+// may be empty, so --tags= empties the list.  There is no token by default,
+// see UnsetClearToken.
+//
+// Example with the token "none":
+//   prog --tags a b --tags none c   // tags is [c]
+func (f *FlagSet) SetClearToken(s string) {
+	f.clearToken = s
+	f.clearTokenSet = true
+}
+
+// SetClearToken sets a value which empties a command-line flag collecting a
+// list of values rather than being added to it.
+func SetClearToken(s string) {
+	CommandLine.SetClearToken(s)
+}
+
+// UnsetClearToken removes the token set with SetClearToken, so every value is
+// added to a list as usual.
+func (f *FlagSet) UnsetClearToken() {
+	f.clearToken = ""
+	f.clearTokenSet = false
+}
+
+// UnsetClearToken removes the token set with SetClearToken for the
+// command-line flags.
+func UnsetClearToken() {
+	CommandLine.UnsetClearToken()
+}
+
+// SetSliceMutationSyntax turns on a prefix for the first value given to a
+// flag collecting a list of values, such as a StringSlice, so layered config
+// can both add to and replace a list.  A leading "=" replaces the values
+// given so far and a leading "+" adds to them.  Without a prefix, values are
+// added as usual.
+//
+// Example:
+//   prog --tags a --tags =b c   // tags is [b c]
+//   prog --tags a --tags +b     // tags is [a b]
+func (f *FlagSet) SetSliceMutationSyntax(on bool) {
+	f.sliceMutation = on
+}
+
+// SetSliceMutationSyntax turns on the "=" and "+" prefixes for command-line
+// flags collecting a list of values, see FlagSet.SetSliceMutationSyntax.
+func SetSliceMutationSyntax(on bool) {
+	CommandLine.sliceMutation = on
+}
+
+// SetValueIndirection turns on resolving flag values of the form @env:NAME
+// from the environment variable NAME, and @file:PATH from the contents of the
+// file at PATH, without a final newline.  This keeps secrets out of the shell
+// history and the process list, and parse errors show the values as given.
+//
+// Example:
+//   prog --token @env:API_TOKEN --key @file:/run/secrets/key
+func (f *FlagSet) SetValueIndirection(on bool) {
+	f.valueIndirection = on
+}
+
+// SetValueIndirection turns on resolving command-line flag values of the form
+// @env:NAME and @file:PATH, see FlagSet.SetValueIndirection.
+func SetValueIndirection(on bool) {
+	CommandLine.valueIndirection = on
+}
+
+// SetEchoArgsOnError tells the parser to print the command line it was given
+// before the message for a parse error, such as
+//   while parsing: prog --count x
+// which helps when reading the logs of a failed invocation.
+func (f *FlagSet) SetEchoArgsOnError(echo bool) {
+	f.echoArgsOnError = echo
+}
+
+// SetEchoArgsOnError tells the parser to print the command line it was given
+// before the message for a parse error of the command-line flags.
+func SetEchoArgsOnError(echo bool) {
+	CommandLine.echoArgsOnError = echo
+}
+
+// BindPassthrough stores all the arguments following the "--" terminator into
+// p, instead of appending them to Args.  This is helpful for wrapper programs
+// which pass the remaining arguments on to another command.
+//
+// Example:
+//   prog -v input -- cmd --flag   // Args() is [input], p is [cmd --flag]
+func (f *FlagSet) BindPassthrough(p *[]string) {
+	f.passthrough = p
+}
+
+// BindPassthrough stores all the command-line arguments following the "--"
+// terminator into p, instead of appending them to Args.
+func BindPassthrough(p *[]string) {
+	CommandLine.passthrough = p
+}
+
+// VisitAll visits the flags in lexicographical order, calling fn for each.
+// It visits all flags, even those not set.
+func (f *FlagSet) VisitAll(fn func(*Flag)) {
+	for _, flag := range sortFlags(f.formal) {
+		fn(flag)
+	}
+}
+
+// VisitAll visits the command-line flags in lexicographical order, calling
+// fn for each.  It visits all flags, even those not set.
+func VisitAll(fn func(*Flag)) {
+	CommandLine.VisitAll(fn)
+}
+
+// Visit visits the flags in lexicographical order, calling fn for each.
+// It visits only those flags that have been set.
+func (f *FlagSet) Visit(fn func(*Flag)) {
+	for _, flag := range sortFlags(f.actual) {
+		fn(flag)
+	}
+}
+
+// Visit visits the command-line flags in lexicographical order, calling fn
+// for each.  It visits only those flags that have been set.
+func Visit(fn func(*Flag)) {
+	CommandLine.Visit(fn)
+}
+
+// Lookup returns the Flag structure of the named flag, returning nil if none exists.
+func (f *FlagSet) Lookup(name string) *Flag {
+	if f.mulock == nil {
+		f.mulock = new(sync.Mutex)
+	}
+	f.mulock.Lock()
+	defer f.mulock.Unlock()
+	for _, flag := range f.formal {
+		for _, n := range flag.Name {
+			if name == n {
+				return flag
+			}
+		}
+	}
+	if a, ok := f.aliases[name]; ok {
+		return a.flag
+	}
+	return nil
+}
+
+// Resolve returns the flag which Parse would set for the token, such as
+// "--verbose", "--name=x", "-v" or "-vx", where a cluster of single-rune flags
+// resolves to the first.  A single dash long name is resolved first if
+// SetSingleDashLong is set, an indexed name, such as "--item[1]", resolves to
+// its indexed flag and an expansion to the flag its first argument sets.  It
+// reports false if the token is not a flag or names no defined flag.  This is
+// helpful for tooling such as completion.
+func (f *FlagSet) Resolve(token string) (*Flag, bool) {
+	if len(token) < 2 || token[0] != '-' || token == "--" {
+		return nil, false
+	}
+	name, _, _, attached := f.splitFlagArg(token)
+	if exp, ok := f.expansions[name]; ok {
+		if attached || len(exp) == 0 {
+			return nil, false
+		}
+		return f.Resolve(exp[0])
+	}
+	flag := f.lookupArg(name)
+	return flag, flag != nil
+}
+
+// Resolve returns the command-line flag which Parse would set for the token,
+// see FlagSet.Resolve.
+func Resolve(token string) (*Flag, bool) {
+	return CommandLine.Resolve(token)
+}
+
+// Lookup returns the Flag structure of the named command-line flag,
+// returning nil if none exists.
+func Lookup(name string) *Flag {
+	return CommandLine.Lookup(name)
+}
+
+// mustLookup returns the named flag, panicking if it has not been defined.
+func (f *FlagSet) mustLookup(name string) *Flag {
+	flag := f.Lookup(name)
+	if flag == nil {
+		fmt.Fprintf(f.ErrorOutput(), "%s %v not defined: %s\n", f.name, f.FlagKnownAs, name)
+		panic(fmt.Sprintf("%v not defined", f.FlagKnownAs)) // Happens only if flags are marked before being declared
+	}
+	return flag
+}
+
+// SetAnnotation stores a key and value on the named flag, as metadata for
+// tools such as completion generators, documentation or UIs, for example
+// "category" with "advanced".  The parser itself ignores annotations.
+func (f *FlagSet) SetAnnotation(name, key, value string) {
+	flag := f.mustLookup(name)
+	if flag.Annotations == nil {
+		flag.Annotations = make(map[string]string)
+	}
+	flag.Annotations[key] = value
+}
+
+// SetAnnotation stores a key and value on the named command-line flag, as
+// metadata for external tooling.
+func SetAnnotation(name, key, value string) {
+	CommandLine.SetAnnotation(name, key, value)
+}
+
+// Annotation returns the value stored by SetAnnotation for key on the named
+// flag, and whether there was one.
+func (f *FlagSet) Annotation(name, key string) (value string, ok bool) {
+	if flag := f.Lookup(name); flag != nil {
+		value, ok = flag.Annotations[key]
+	}
+	return
+}
+
+// Annotation returns the value stored by SetAnnotation for key on the named
+// command-line flag, and whether there was one.
+func Annotation(name, key string) (value string, ok bool) {
+	return CommandLine.Annotation(name, key)
+}
+
+// MarkUTF8 requires the values given to the named flag to be valid UTF-8, for
+// values such as names and labels which are embedded in text protocols.
+func (f *FlagSet) MarkUTF8(name string) {
+	f.mustLookup(name).UTF8 = true
+}
+
+// MarkUTF8 requires the values given to the named command-line flag to be
+// valid UTF-8.
+func MarkUTF8(name string) {
+	CommandLine.MarkUTF8(name)
+}
+
+// MarkTrimSpace trims leading and trailing white space from each value given
+// to the named flag before it is set, as pasted values often carry a stray
+// space which leads to errors such as a file not being found.
+func (f *FlagSet) MarkTrimSpace(name string) {
+	f.mustLookup(name).TrimSpace = true
+}
+
+// MarkTrimSpace trims white space from each value given to the named
+// command-line flag before it is set.
+func MarkTrimSpace(name string) {
+	CommandLine.MarkTrimSpace(name)
+}
+
+// MarkExperimental marks the named flag as experimental, leaving it out of the
+// help and making Parse fail when it is set without --experimental.  A hidden
+// present flag named "experimental" is defined as the gate, unless a flag by
+// that name is already defined, so it may be enabled by other means such as an
+// environment variable.
+func (f *FlagSet) MarkExperimental(name string) {
+	flag := f.mustLookup(name)
+	flag.Experimental = true
+	flag.Hidden = true
+	if f.Lookup("experimental") == nil {
+		f.Pres("experimental", fmt.Sprintf("enable experimental %vs", f.FlagKnownAs))
+		f.Lookup("experimental").Hidden = true
+	}
+}
+
+// MarkExperimental marks the named command-line flag as experimental.
+func MarkExperimental(name string) {
+	CommandLine.MarkExperimental(name)
+}
+
+// checkExperimental ensures no experimental flag has been set unless the
+// experimental gate has been too.
+func (f *FlagSet) checkExperimental() error {
+	gate := f.Lookup("experimental")
+	if gate == nil || f.isSet(gate) {
+		return nil
+	}
+	for _, flag := range f.formal {
+		if flag.Experimental && f.isSet(flag) {
+			return f.failFlag(&ParseError{Name: flag.Name[0], Reason: ReasonExperimental},
+				"%v %s is experimental; pass --experimental to enable", f.FlagKnownAs, flagWithMinus(flag.Name[0]))
+		}
+	}
+	return nil
+}
+
+// MarkNonNegative requires the value of the named int, float or duration flag
+// to be zero or more, so "--offset -5s" is an error rather than a negative
+// offset, and the value is left as it was.  MarkNonNegative panics for other
+// flags, such as lists and unsigned ints, and for Values not of this package,
+// as the value could not be put back.
+func (f *FlagSet) MarkNonNegative(name string) {
+	flag := f.mustLookup(name)
+	if _, ok := isNegative(flag.Value); !ok || saveNumber(flag.Value) == nil {
+		fmt.Fprintf(f.ErrorOutput(), "%s %v cannot be checked for negative values: %s\n", f.name, f.FlagKnownAs, name)
+		panic(fmt.Sprintf("%v cannot be checked for negative values", f.FlagKnownAs))
+	}
+	flag.NonNegative = true
+}
+
+// MarkNonNegative requires the value of the named command-line flag to be
+// zero or more.
+func MarkNonNegative(name string) {
+	CommandLine.MarkNonNegative(name)
+}
+
+// SetPattern requires the values given to the named flag to match the regular
+// expression regex, such as `^[^:]+:[0-9]+$` for host:port.  If the flag has
+// no type expected, the pattern is shown in its place in the help.  SetPattern
+// panics if regex does not compile.
+func (f *FlagSet) SetPattern(name, regex string) {
+	flag := f.mustLookup(name)
+	flag.Pattern = regexp.MustCompile(regex)
+	if flag.TypeExpected == "" {
+		flag.TypeExpected = regex
+	}
+}
+
+// SetPattern requires the values given to the named command-line flag to
+// match the regular expression regex, see FlagSet.SetPattern.
+func SetPattern(name, regex string) {
+	CommandLine.SetPattern(name, regex)
+}
+
+// Set sets the value of the named flag.
+func (f *FlagSet) Set(name string, value []string) error {
+	f.mulock.Lock()
+	defer f.mulock.Unlock()
+	var flag *Flag
+	for _, flagTest := range f.formal {
+		for _, n := range flagTest.Name {
+			if n == name {
+				flag = flagTest
+			}
+		}
+	}
+	if flag == nil {
+		return fmt.Errorf("no such %v -%v", f.FlagKnownAs, name)
+	}
+	err := flag.set(name, value)
+	if err != nil {
+		return err
+	}
+	if f.actual == nil {
+		f.actual = make([]*Flag, 0)
+	}
+	f.actual = append(f.actual, flag)
+	return nil
+}
+
+// Set sets the value of the named command-line flag.
+func Set(name string, value []string) error {
+	return CommandLine.Set(name, value)
+}
+
+// SetFromString sets the named flag from a single argument, as if raw had
+// followed the flag on the command line.  Errors are wrapped the same way as
+// Parse does, which makes this helpful for testing custom Values.
+func (f *FlagSet) SetFromString(name, raw string) error {
+	flag := f.Lookup(name)
+	if flag == nil {
+		return &ParseError{Name: name, Reason: ReasonUndefined,
+			msg: fmt.Sprintf("%v provided but not defined: %s", f.FlagKnownAs, flagWithMinus(name))}
+	}
+	if err := flag.set(name, []string{raw}); err != nil {
+		pe := &ParseError{Name: name, Value: []string{raw}, Reason: ReasonInvalid, Err: err}
+		if raw == "" {
+			pe.Reason = ReasonEmpty
+			pe.msg = fmt.Sprintf("empty value for %v %s", f.FlagKnownAs, flagWithMinus(name))
+			return pe
+		}
+		pe.msg = fmt.Sprintf("invalid value %q for %v %s: %v", raw, f.FlagKnownAs, flagWithMinus(name), err)
+		return pe
+	}
+	f.mulock.Lock()
+	defer f.mulock.Unlock()
+	f.actual = append(f.actual, flag)
+	return nil
+}
+
+// SetFromString sets the named command-line flag from a single argument, as
+// if raw had followed the flag on the command line.
+func SetFromString(name, raw string) error {
+	return CommandLine.SetFromString(name, raw)
+}
+
+/*
+// flagsByLength is a slice of flags implementing sort.Interface,
+// sorting primarily by the length of the flag, and secondarily
+// alphabetically.
+type flagsByLength []*Flag
+
+func (f flagsByLength) Less(i, j int) bool {
+	s1, s2 := f[i].Name, f[j].Name
+	if len(s1) != len(s2) {
+		return len(s1) < len(s2)
+	}
+	return s1 < s2
+}
+func (f flagsByLength) Swap(i, j int) {
+	f[i], f[j] = f[j], f[i]
+}
+func (f flagsByLength) Len() int {
+	return len(f)
+}
+*/
+
+// flagsByName is a slice of slices of flags implementing sort.Interface,
+// alphabetically sorting by the name of the first flag in each slice.
+type flagsByName []*Flag
+
+func (f flagsByName) Less(i, j int) bool {
+	var a, b int
+	if len(f[i].Name) > 1 && rlen(f[i].Name[0]) == 1 && rlen(f[i].Name[1]) > 1 {
+		a = 1
+	}
+	if len(f[j].Name) > 1 && rlen(f[j].Name[0]) == 1 && rlen(f[j].Name[1]) > 1 {
+		b = 1
+	}
+	return f[i].Name[a] < f[j].Name[b]
+}
+func (f flagsByName) Swap(i, j int) {
+	f[i], f[j] = f[j], f[i]
+}
+func (f flagsByName) Len() int {
+	return len(f)
+}
+
+/*
+func (f *FlagSet) ptrToVal(ptr string) (ret Value) {
+	f.VisitAll(func(f *Flag) {
+		if fmt.Sprintf("%#v") == ptr {
+			ret = f.Value
+		}
+	})
+	return
+}
+*/
+
+func rlen(s string) int {
+	return utf8.RuneCount([]byte(s))
+}
+
+// PrintDefaults prints, to standard error unless configured
+// otherwise, the default values of all defined flags in the set.
+// If there is more than one name for a given flag, the usage information and
+// default value from the shortest will be printed (or the least alphabetically
+// if there are several equally short flag names).
+// With ShowGroupings, the groups are printed in the order in which they were
+// first given to GroupingSet, and within each group the flags are sorted by
+// name, using the long name of flags which have both a long and a short one.
+func (f *FlagSet) PrintDefaults() {
+	f.writeDefaults(f.Output())
+}
+
+// writeDefaults writes the default values of all defined flags in the set to w.
+func (f *FlagSet) writeDefaults(w io.Writer) {
+	for _, entry := range f.DefaultLines() {
+		fmt.Fprintf(w, "%s\n", entry)
+	}
+}
+
+// DefaultLines returns the entries PrintDefaults would write, one for each
+// flag and grouping header, without the trailing newlines.  An entry spans
+// more than one line when the usage has newlines or is wrapped.
+func (f *FlagSet) DefaultLines() []string {
+	//var maxLen int
+	var haveMultiple, haveSingleChar bool
+	// group together all flags for a given value
+	var flags [](*Flag)
+	var nameAndTypeLen []int
+	var groupings []string
+	var groupingsCount = map[string]int{}
+
+	f.mulock.Lock()
+loop_formals:
+	for _, flag := range f.formal {
+		if flag.Hidden {
+			continue
+		}
+		for _, grp := range groupings {
+			if grp == flag.Grouping {
+				groupingsCount[flag.Grouping]++
+				continue loop_formals
+			}
+		}
+		groupings = append(groupings, flag.Grouping)
+		groupingsCount[flag.Grouping] = 1
+	}
+	f.mulock.Unlock()
+
+	var avgLen float64
+	//var uniqueFlag = make(map[string]interface{})
+	f.VisitAll(func(flag *Flag) {
+		if flag.Hidden {
+			return
+		}
+		//if _, ok := uniqueFlag[flag.Name[0]]; !ok {
+		//uniqueFlag[flag.Name[0]] = nil
+		flags = append(flags, flag)
+
+		// Set boolean if we have multiple flags set
+		if len(flag.Name) > 1 {
+			haveMultiple = true
+		}
+		// Check if we have single char flags
+		for _, name := range flag.Name {
+			if rlen(name) == 1 {
+				haveSingleChar = true
+			}
+		}
+
+		if f.UsageIndent == 0 {
+			myLen := displayWidth(f.nameSep())*(len(flag.Name)-1) + f.UsageSpace + f.Indent
+			for _, name := range flag.Name {
+				myLen += displayWidth(name)
+			}
+
+			// Math to determine width needed
+			if flag.TypeExpected != "" {
+				withTypeLen := myLen + f.TypeSpace + displayWidth(flag.TypeExpected)
+				nameAndTypeLen = append(nameAndTypeLen, withTypeLen)
+				avgLen += float64(withTypeLen)
+			} else {
+				nameAndTypeLen = append(nameAndTypeLen, myLen)
+				avgLen += float64(myLen)
+			}
+		}
+		//}
+	})
+
+	var usageIndent int
+
+	if f.UsageIndent == 0 {
+		avgLen /= float64(len(nameAndTypeLen))
+		var stdDev float64
+		for _, l := range nameAndTypeLen {
+			stdDev += math.Pow(math.Abs(float64(l)-avgLen), 0.5)
+		}
+		stdDev = math.Pow(stdDev/float64(len(nameAndTypeLen)), 2)
+
+		usageIndent = int(avgLen + stdDev*1.6)
+	} else {
+		usageIndent = f.UsageIndent
+	}
+
+	//sort.Sort(flags)
+
+	// sort the output flags by shortest name for each group.
+	//var byName flagsByName
+	//for _, f := range flags {
+	//	sort.Sort(f)
+	//	byName = append(byName, f)
+	//}
+	pad := "\n"
+	for len(pad) <= usageIndent {
+		pad += " "
+	}
+
+	// rows of output, the default annotation is kept separate to allow for
+	// alignment into a column
+	type row struct{ text, def string }
+	var rows []row
+
+	var line bytes.Buffer
+	for _, grp := range groupings {
+		if f.ShowGroupings {
+			// Print group headers
+			rows = append(rows, row{text: f.GroupingHeaders(grp, groupingsCount[grp])})
+			/*plural := ""
+			if groupingsCount[grp] > 1 {
+				plural = "s"
+			}
+			if grp == "" {
+				fmt.Fprintf(w, "Option%s:\n", plural)
+			} else {
+				fmt.Fprintf(w, "%s option%s:\n", grp, plural)
+			}*/
+		}
+
+		for _, fs := range flags {
+			if f.ShowGroupings {
+				// Skip all flags not assigned to this group
+				if fs.Grouping != grp {
+					continue
+				}
+			}
+
+			Names := displayNames(fs)
+			line.Reset()
+			for j := 0; j < f.Indent; j++ {
+				line.WriteString(" ")
+			}
+			if haveSingleChar && haveMultiple && rlen(Names[0]) > 1 && len(Names) == 1 {
+				// Indent if we have multiple and single char flags are found
+				for j := displayWidth(f.nameSep()) + 2; j > 0; j-- {
+					line.WriteString(" ")
+				}
+			}
+			for i, n := range Names {
+				// Put separators between flags
+				if i > 0 {
+					line.WriteString(f.nameSep())
+				}
+				line.WriteString(flagWithMinus(n))
+			}
+			if len(fs.TypeExpected) > 0 {
+				// Put space before type
+				for j := 0; j < f.TypeSpace; j++ {
+					line.WriteString(" ")
+				}
+				line.WriteString(fs.TypeExpected)
+			}
+			// Put space before usage
+			for j := 0; j < f.UsageSpace; j++ {
+				line.WriteString(" ")
+			}
+			usage := f.usageText(fs)
+			def := f.defValue(fs)
+			inline := strings.Contains(fs.Usage, DefaultToken)
+
+			for displayWidth(line.String()) < usageIndent {
+				line.WriteString(" ")
+			}
+
+			if f.Width > 0 {
+				usage = wrapText(usage, f.Width-displayWidth(line.String()), f.Width-usageIndent)
+			}
+			usage = strings.ReplaceAll(usage, "\n", pad)
+			r := row{text: line.String() + usage}
+			if fs.Required {
+				// required flags have no default which would be used
+				r.def = "(required)"
+				rows = append(rows, r)
+				continue
+			}
+			if isPresent(fs) || inline {
+				// present flags have no default to show, whatever their value,
+				// and inline defaults are already shown
+				rows = append(rows, r)
+				continue
+			}
+			switch baseValue(fs.Value).(type) {
+			case *stringSliceValue, *intSliceValue, *float64SliceValue, *durationSliceValue,
+				*stringMapValue, *stringListValue, *indexedStringSliceValue:
+				// no default to show
+			case *stringValue, *enumStringValue, *pathValue, *flagFuncIndexedValue:
+				// put quotes on string values and empty func values
+				if f.ShowDefaultVal {
+					r.def = fmt.Sprintf("(%s%q)", Default, def)
+				}
+			case *boolValue, *lenientBoolValue:
+				if f.ShowDefaultVal && !(f.hideFalseBool && def == "false") {
+					r.def = fmt.Sprintf("(%s%s)", Default, f.boolDisplay(def))
+				}
+			case *flagFuncValue:
+				// put quotes on empty func values, but not on a default display
+				if f.ShowDefaultVal && def == "" {
+					r.def = fmt.Sprintf("(%s%q)", Default, def)
+				} else if f.ShowDefaultVal {
+					r.def = fmt.Sprintf("(%s%s)", Default, def)
+				}
+			default:
+				if f.ShowDefaultVal {
+					r.def = fmt.Sprintf("(%s%s)", Default, def)
+				}
+			}
+			if rg, ok := baseValue(fs.Value).(ranged); ok {
+				r.def = strings.TrimSpace(r.def + " (Range: " + rg.Range() + ")")
+			}
+			if c, ok := baseValue(fs.Value).(choices); ok {
+				r.def = strings.TrimSpace(r.def + " (one of: " + strings.Join(c.Choices(), ",") + ")")
+			}
+			rows = append(rows, r)
+		}
+
+		if !f.ShowGroupings {
+			break
+		}
+	}
+
+	// Find the column for the default annotations
+	var defCol int
+	if f.alignDefaults {
+		for _, r := range rows {
+			if r.def != "" {
+				if width := lastLineWidth(r.text); width > defCol {
+					defCol = width
+				}
+			}
+		}
+	}
+
+	var entries []string
+	for _, r := range rows {
+		if r.def == "" {
+			entries = append(entries, r.text)
+			continue
+		}
+		line.Reset()
+		line.WriteString(r.text)
+		for j := lastLineWidth(r.text); j < defCol; j++ {
+			line.WriteString(" ")
+		}
+		entries = append(entries, line.String()+"  "+r.def)
+	}
+	return entries
+}
+
+// DefaultLines returns the entries PrintDefaults would write for the
+// command-line flags, see FlagSet.DefaultLines.
+func DefaultLines() []string {
+	return CommandLine.DefaultLines()
+}
+
+// SetDefaultTemplate sets the default shown in the help for the named flag to
+// the result of the text/template tmpl executed with data, such as
+// "{{.Hostname}}", when the help is printed.  This is for defaults which
+// depend on where or when the program runs.  The DefValue is shown if the
+// template fails.  SetDefaultTemplate panics if tmpl does not parse.
+func (f *FlagSet) SetDefaultTemplate(name, tmpl string, data interface{}) {
+	flag := f.mustLookup(name)
+	if f.defaultTemplates == nil {
+		f.defaultTemplates = make(map[*Flag]defaultTemplate)
+	}
+	f.defaultTemplates[flag] = defaultTemplate{template.Must(template.New(name).Parse(tmpl)), data}
+}
+
+// SetDefaultTemplate sets the default shown in the help for the named
+// command-line flag to the result of a template, see FlagSet.SetDefaultTemplate.
+func SetDefaultTemplate(name, tmpl string, data interface{}) {
+	CommandLine.SetDefaultTemplate(name, tmpl, data)
+}
+
+// defValue returns the default to show in the help for the flag.
+func (f *FlagSet) defValue(flag *Flag) string {
+	if t, ok := f.defaultTemplates[flag]; ok {
+		var buf bytes.Buffer
+		if err := t.tmpl.Execute(&buf, t.data); err == nil {
+			return buf.String()
+		}
+	}
+	return flag.DefValue
+}
+
+// usageText returns the usage of the flag with any DefaultToken replaced by
+// its default, as shown in the help.
+func (f *FlagSet) usageText(flag *Flag) string {
+	return strings.ReplaceAll(flag.Usage, DefaultToken, f.defValue(flag))
+}
+
+// isPresent reports whether the flag is a present flag, one which is set just
+// by being given and takes no value.
+func isPresent(flag *Flag) bool {
+	if p, ok := flag.Value.(presentFlag); ok && p.IsPresentFlag() {
+		return true
+	}
+	_, optional := baseValue(flag.Value).(optionalArg)
+	return flag.ArgsNeeded == 0 && !optional
+}
+
+// wrapText breaks each line of s at spaces so the first line fits in first
+// columns and the following lines fit in rest columns.  Words wider than the
+// space available are left on a line of their own.
+func wrapText(s string, first, rest int) string {
+	var out bytes.Buffer
+	avail := first
+	for i, para := range strings.Split(s, "\n") {
+		if i > 0 {
+			out.WriteString("\n")
+			avail = rest
+		}
+		var col int
+		for j, word := range strings.Fields(para) {
+			width := displayWidth(word)
+			if j > 0 {
+				if col+1+width > avail {
+					out.WriteString("\n")
+					avail, col = rest, 0
+				} else {
+					out.WriteString(" ")
+					col++
+				}
+			}
+			out.WriteString(word)
+			col += width
+		}
+	}
+	return out.String()
+}
+
+// displayNames returns the names of the flag in the order they are shown in
+// help, with a single char name first.
+func displayNames(flag *Flag) []string {
+	names := append([]string{}, flag.Name...)
+	if len(names) > 1 && rlen(names[0]) > 1 && rlen(names[1]) == 1 {
+		names[0], names[1] = names[1], names[0]
+	}
+	return names
+}
+
+// lastLineWidth returns the display width of the last line in s.
+func lastLineWidth(s string) int {
+	return displayWidth(s[strings.LastIndex(s, "\n")+1:])
+}
+
+// displayWidth returns the number of columns s takes up on a terminal, not
+// counting ANSI escape sequences, such as those for color.
+func displayWidth(s string) int {
+	if !strings.Contains(s, "\x1b") {
+		return runewidth.StringWidth(s)
+	}
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\x1b' {
+			buf.WriteByte(s[i])
+			continue
+		}
+		switch {
+		case i+1 < len(s) && s[i+1] == '[':
+			// control sequence, ended by a byte in the range @ to ~
+			for i += 2; i < len(s) && (s[i] < 0x40 || s[i] > 0x7e); i++ {
+			}
+		case i+1 < len(s) && s[i+1] == ']':
+			// operating system command, ended by BEL or ESC \
+			for i += 2; i < len(s) && s[i] != '\a'; i++ {
+				if s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '\\' {
+					i++
+					break
+				}
+			}
+		default:
+			// two byte escape
+			i++
+		}
+	}
+	return runewidth.StringWidth(buf.String())
+}
+
+// LintUsage checks the documentation of the flags in the set and returns an
+// error for each flag which has no usage, or which takes a value and has no
+// hint on the type expected.  A required flag with a non-zero default is also
+// reported, as the default masks the requirement in the usage.  Hidden flags
+// are not checked.  This is helpful for enforcing documentation standards in
+// tests.
+func (f *FlagSet) LintUsage() []error {
+	var errs []error
+	seen := make(map[*Flag]bool)
+	f.VisitAll(func(flag *Flag) {
+		if seen[flag] || flag.Hidden {
+			return
+		}
+		seen[flag] = true
+		name := flagWithMinus(flag.Name[0])
+		if strings.TrimSpace(flag.Usage) == "" {
+			errs = append(errs, fmt.Errorf("%v %s has no usage", f.FlagKnownAs, name))
+		}
+		if flag.ArgsNeeded != 0 && flag.TypeExpected == "" {
+			errs = append(errs, fmt.Errorf("%v %s has no type expected", f.FlagKnownAs, name))
+		}
+		if flag.Required && hasDefault(flag) {
+			errs = append(errs, fmt.Errorf("%v %s is required and has the default %q",
+				f.FlagKnownAs, name, flag.DefValue))
+		}
+	})
+	return errs
+}
+
+// hasDefault reports whether the flag has a default other than the zero value
+// of its type.
+func hasDefault(flag *Flag) bool {
+	if isPresent(flag) {
+		return false
+	}
+	switch flag.DefValue {
+	case "", "0", "false", "[]", "0s":
+		return false
+	}
+	return true
+}
+
+// CheckClusters returns an error for each long name which could be confused
+// with a cluster of single-rune flags, such as a flag named "ab" when both -a
+// and -b are defined, as "-ab" then sets -a and -b rather than --ab.  A
+// cluster ends at a flag which takes a value, so with -a taking a value, "ab"
+// is confused with -a b.  This is helpful for checking definitions in tests.
+func (f *FlagSet) CheckClusters() []error {
+	var errs []error
+	f.VisitAll(func(flag *Flag) {
+		for _, name := range flag.Name {
+			if rlen(name) < 2 {
+				continue
+			}
+			confused := true
+			for _, r := range name {
+				short := f.Lookup(string(r))
+				if short == nil {
+					confused = false
+					break
+				}
+				if short.ArgsNeeded != 0 {
+					break
+				}
+			}
+			if confused {
+				errs = append(errs, fmt.Errorf("%v --%s could be confused with the cluster -%s",
+					f.FlagKnownAs, name, name))
+			}
+		}
+	})
+	return errs
+}
+
+// PrintSummary prints to w a terse overview of all defined flags in the set,
+// one line per flag with the names and usage only, leaving out the types
+// expected and the default values.  The usage is wrapped to Width if set.
+func (f *FlagSet) PrintSummary(w io.Writer) {
+	var flags []*Flag
+	var names []string
+	var col int
+	f.VisitAll(func(flag *Flag) {
+		if flag.Hidden {
+			return
+		}
+		var line bytes.Buffer
+		for i, n := range displayNames(flag) {
+			if i > 0 {
+				line.WriteString(f.nameSep())
+			}
+			line.WriteString(flagWithMinus(n))
+		}
+		if width := displayWidth(line.String()); width > col {
+			col = width
+		}
+		flags = append(flags, flag)
+		names = append(names, line.String())
+	})
+	col += f.Indent + 2
+
+	pad := "\n" + strings.Repeat(" ", col)
+	for i, flag := range flags {
+		usage := strings.Join(strings.Fields(f.usageText(flag)), " ")
+		if f.Width > 0 {
+			usage = wrapText(usage, f.Width-col, f.Width-col)
+		}
+		usage = strings.ReplaceAll(usage, "\n", pad)
+		line := strings.Repeat(" ", f.Indent) + names[i]
+		fmt.Fprintf(w, "%s%s%s\n", line, strings.Repeat(" ", col-displayWidth(line)), usage)
+	}
+}
+
+// PrintSummary prints to w a terse overview of all defined command-line flags,
+// one line per flag with the names and usage only.
+func PrintSummary(w io.Writer) {
+	CommandLine.PrintSummary(w)
+}
+
+// WriteFishCompletion writes to w a fish shell completion script for progName,
+// with a "complete" command for each flag in the set giving its names and
+// usage.  Flags which take a value are marked with -r.  Hidden flags are left
+// out.  The script can be saved as ~/.config/fish/completions/progName.fish.
+func (f *FlagSet) WriteFishCompletion(w io.Writer, progName string) {
+	quote := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	f.VisitAll(func(flag *Flag) {
+		if flag.Hidden {
+			return
+		}
+		line := []string{"complete", "-c", progName}
+		for _, n := range displayNames(flag) {
+			if rlen(n) == 1 {
+				line = append(line, "-s", n)
+			} else {
+				line = append(line, "-l", n)
+			}
+		}
+		if flag.ArgsNeeded != 0 {
+			line = append(line, "-r")
+		}
+		if usage := strings.Join(strings.Fields(f.usageText(flag)), " "); usage != "" {
+			line = append(line, "-d", "'"+quote.Replace(usage)+"'")
+		}
+		fmt.Fprintln(w, strings.Join(line, " "))
+	})
+}
+
+// WriteFishCompletion writes to w a fish shell completion script for the
+// command-line flags, see FlagSet.WriteFishCompletion.
+func WriteFishCompletion(w io.Writer, progName string) {
+	CommandLine.WriteFishCompletion(w, progName)
+}
+
+// WriteINIDefaults writes to w the flags in the set with their defaults in INI
+// format, as a template for a config file.  Each flag is written once, by its
+// long name, as "name = default" after its usage as a comment, and flags in a
+// group follow the group name as a [Section] header, after those in no group.  Flags with an empty
+// default are commented out.  Hidden flags are left out.
+func (f *FlagSet) WriteINIDefaults(w io.Writer) {
+	// flags in no group come first, as they are outside any [Section]
+	groups := []string{""}
+	seen := map[string]bool{"": true}
+	for _, flag := range f.formal {
+		if !flag.Hidden && !seen[flag.Grouping] {
+			seen[flag.Grouping] = true
+			groups = append(groups, flag.Grouping)
+		}
+	}
+	for _, grp := range groups {
+		if grp != "" {
+			fmt.Fprintf(w, "[%s]\n\n", grp)
+		}
+		f.VisitAll(func(flag *Flag) {
+			if flag.Hidden || flag.Grouping != grp {
+				return
+			}
+			for _, line := range strings.Split(strings.TrimSpace(f.usageText(flag)), "\n") {
+				fmt.Fprintf(w, "# %s\n", strings.TrimSpace(line))
+			}
+			switch def := f.defValue(flag); def {
+			case "", "[]":
+				fmt.Fprintf(w, "# %s = \n\n", flag.Name[0])
+			default:
+				fmt.Fprintf(w, "%s = %s\n\n", flag.Name[0], def)
+			}
+		})
+	}
+}
+
+// WriteINIDefaults writes to w the command-line flags with their defaults in
+// INI format, see FlagSet.WriteINIDefaults.
+func WriteINIDefaults(w io.Writer) {
+	CommandLine.WriteINIDefaults(w)
+}
+
+// PrintDefaults prints to standard error the default values of all defined command-line flags.
+func PrintDefaults() {
+	CommandLine.PrintDefaults()
+}
+
+// defaultUsage is the default function to print a usage message.
+func defaultUsage(f *FlagSet) {
+	f.writeDescription(f.Output())
+	f.PrintDefaults()
+	f.writeFooter(f.Output())
+}
+
+// NOTE: Usage is not just defaultUsage(CommandLine)
+// because it serves (via godoc flag Usage) as the example
+// for how to write your own usage function.
+
+// Usage prints to standard error a usage message documenting all defined command-line flags.
+// The function is a variable that may be changed to point to a custom function.
+var Usage = func() {
+	CommandLine.writeHeader(CommandLine.Output())
+	PrintDefaults()
+	CommandLine.writeFooter(CommandLine.Output())
+}
+
+// writeHeader writes the title, the usage line and the description for the
+// flag set to w.
+func (f *FlagSet) writeHeader(w io.Writer) {
+	if len(f.Title) > 0 {
+		fmt.Fprintf(w, "%s\n\n", f.Title)
+	}
+	post := ""
+	if len(f.Params) > 0 {
+		post = "[options...] [args...]"
+	} else if len(f.formal) > 1 {
+		post = "[options...]"
+	} else {
+		post = "[option]"
+	}
+	fmt.Fprintf(w, "Usage: %s %s\n", f.progName(), post)
+	if f.description != "" {
+		fmt.Fprintln(w)
+		f.writeDescription(w)
+	}
+}
+
+// writeDescription writes the text given to SetDescription, if any, to w.
+func (f *FlagSet) writeDescription(w io.Writer) {
+	if f.description == "" {
+		return
+	}
+	text := f.description
+	if f.Width > 0 {
+		text = wrapText(text, f.Width, f.Width)
+	}
+	fmt.Fprintf(w, "%s\n\n", text)
+}
+
+// SetDescription sets text to be shown in the usage between the usage line
+// and the flags, such as a short paragraph on what the program does.  The
+// text is wrapped to Width if set.
+func (f *FlagSet) SetDescription(text string) {
+	f.description = text
+}
+
+// SetDescription sets text to be shown in the usage for the command-line
+// flags between the usage line and the flags.
+func SetDescription(text string) {
+	CommandLine.description = text
+}
+
+// writeFooter writes the sections which follow the flags in the usage to w,
+// the examples and then the epilogue.
+func (f *FlagSet) writeFooter(w io.Writer) {
+	if len(f.examples) > 0 {
+		fmt.Fprintf(w, "\nExamples:\n")
+		for _, ex := range f.examples {
+			desc := ex.description
+			if f.Width > 0 {
+				desc = wrapText(desc, f.Width-2, f.Width-2)
+			}
+			fmt.Fprintf(w, "  %s\n    %s\n", strings.ReplaceAll(desc, "\n", "\n  "), ex.commandLine)
+		}
+	}
+	if f.epilogue != "" {
+		text := f.epilogue
+		if f.Width > 0 {
+			text = wrapText(text, f.Width, f.Width)
+		}
+		fmt.Fprintf(w, "\n%s\n", text)
+	}
+}
+
+// SetEpilogue sets text to be shown at the end of the usage, after the flags
+// and any examples, for notes such as "Report bugs to ...".  The text is
+// wrapped to Width if set.
+func (f *FlagSet) SetEpilogue(text string) {
+	f.epilogue = text
+}
+
+// SetEpilogue sets text to be shown at the end of the usage for the
+// command-line flags.
+func SetEpilogue(text string) {
+	CommandLine.epilogue = text
+}
+
+// AddExample adds an example, with a description and the command line, to be
+// shown in an "Examples:" section after the flags in the usage.
+//
+// Example:
+//   fs.AddExample("Compress a file in place:", "prog -z file")
+func (f *FlagSet) AddExample(description, commandLine string) {
+	f.examples = append(f.examples, example{description, commandLine})
+}
+
+// AddExample adds an example to be shown after the command-line flags in the
+// usage.
+func AddExample(description, commandLine string) {
+	CommandLine.AddExample(description, commandLine)
+}
+
+// progName returns the name of the program for messages, the flag set name or
+// the base of os.Args[0] for CommandLine.
+func (f *FlagSet) progName() string {
+	if f == CommandLine || f.name == "" {
+		return path.Base(os.Args[0])
+	}
+	return f.name
+}
+
+// UsageString returns the usage message, the header followed by the default
+// values of all defined flags, any examples and the epilogue, as a string
+// rather than printing it to Output.
+func (f *FlagSet) UsageString() string {
+	var buf bytes.Buffer
+	f.writeHeader(&buf)
+	f.writeDefaults(&buf)
+	f.writeFooter(&buf)
+	return buf.String()
+}
+
+// UsageString returns the usage message for the command-line flags as a
+// string rather than printing it to Output.
+func UsageString() string {
+	return CommandLine.UsageString()
+}
+
+// Usage prints to standard error a usage message documenting all defined command-line flags.
+// The function is a variable that may be changed to point to a custom function.
+var defaultGroupingHeaders = func(name string, count int) string {
+	plural := ""
+	if count > 1 {
+		plural = "s"
+	}
+	if name == "" {
+		return fmt.Sprintf("Option%s:", plural)
+	} else {
+		return fmt.Sprintf("%s option%s:", name, plural)
+	}
+}
+
+// NFlag returns the number of flags that have been set.
+func (f *FlagSet) NFlag() int { return len(f.actual) }
+
+// NFlag returns the number of command-line flags that have been set.
+func NFlag() int { return len(CommandLine.actual) }
+
+// Arg returns the i'th argument.  Arg(0) is the first remaining argument
+// after flags have been processed.
+func (f *FlagSet) Arg(i int) string {
+	if i < 0 || i >= len(f.args) {
+		return ""
+	}
+	return f.args[i]
+}
+
+// Arg returns the i'th command-line argument.  Arg(0) is the first remaining argument
+// after flags have been processed.
+func Arg(i int) string {
+	return CommandLine.Arg(i)
+}
+
+// NArg is the number of arguments remaining after flags have been processed.
+func (f *FlagSet) NArg() int { return len(f.args) }
+
+// NArg is the number of arguments remaining after flags have been processed.
+func NArg() int { return len(CommandLine.args) }
+
+// Args returns the non-flag arguments.
+func (f *FlagSet) Args() []string { return f.args }
+
+// Args returns the non-flag command-line arguments.
+func Args() []string { return CommandLine.args }
+
+// RawArgs returns a copy of the arguments given to Parse, and any following
+// calls to ParseMore, before any were consumed.  This is helpful for logging
+// the exact invocation.
+func (f *FlagSet) RawArgs() []string { return append([]string{}, f.rawArgs...) }
+
+// RawArgs returns a copy of the command-line arguments given to Parse.
+func RawArgs() []string { return CommandLine.RawArgs() }
+
+// PresVar defines a present flag with specified name and usage string.
+// The return value is the address of a bool variable that stores true if seen.
+func (f *FlagSet) PresVar(p *bool, name string, usage string) {
+	f.Var(newPresentValue(p), name, usage, "", 0)
+}
+
+// PresVar defines a present flag with specified name and usage string.
+// The return value is the address of a bool variable that stores true if seen.
+func PresVar(p *bool, name string, usage string) {
+	CommandLine.Var(newPresentValue(p), name, usage, "", 0)
+}
+
+// PresVar defines a present flag with specified name and usage string.
+// The return value is the address of a bool variable that stores true if seen.
+func (f *FlagSet) Pres(name string, usage string) *bool {
+	p := new(bool)
+	f.PresVar(p, name, usage)
+	return p
+}
+
+// Pres defines a present flag with specified name and usage string.
+// The return value is the address of a bool variable that stores true if seen.
+func Pres(name string, usage string) *bool {
+	return CommandLine.Pres(name, usage)
+}
+
+// CountVar defines a count flag with specified name and usage string.
+// The argument p points to an int variable which counts the times the flag is
+// seen, so -vvv or -v -v -v give 3.
+func (f *FlagSet) CountVar(p *int, name string, usage string) {
+	f.Var(newCountValue(p), name, usage, "", 0)
+}
+
+// CountVar defines a count flag with specified name and usage string.
+// The argument p points to an int variable which counts the times the flag is
+// seen.
+func CountVar(p *int, name string, usage string) {
+	CommandLine.Var(newCountValue(p), name, usage, "", 0)
+}
+
+// Count defines a count flag with specified name and usage string.
+// The return value is the address of an int variable that counts the times
+// the flag is seen.
+func (f *FlagSet) Count(name string, usage string) *int {
+	p := new(int)
+	f.CountVar(p, name, usage)
+	return p
+}
+
+// Count defines a count flag with specified name and usage string.
+// The return value is the address of an int variable that counts the times
+// the flag is seen.
+func Count(name string, usage string) *int {
+	return CommandLine.Count(name, usage)
+}
+
+// BoolVar defines a bool flag with specified name, default value, and usage string.
+// The argument p points to a bool variable in which to store the value of the flag.
+func (f *FlagSet) BoolVar(p *bool, name string, value bool, usage string, typeExp string) {
+	f.Var(newBoolValue(value, p), name, usage, typeExp, 1)
+}
+
+// BoolVar defines a bool flag with specified name, default value, and usage string.
+// The argument p points to a bool variable in which to store the value of the flag.
+func BoolVar(p *bool, name string, value bool, usage string, typeExp string) {
+	CommandLine.Var(newBoolValue(value, p), name, usage, typeExp, 1)
+}
+
+// Bool defines a bool flag with specified name, default value, and usage string.
+// The return value is the address of a bool variable that stores the value of the flag.
+func (f *FlagSet) Bool(name string, value bool, usage string, typeExp string) *bool {
+	p := new(bool)
+	f.BoolVar(p, name, value, usage, typeExp)
+	return p
+}
+
+// Bool defines a bool flag with specified name, default value, and usage string.
+// The return value is the address of a bool variable that stores the value of the flag.
+func Bool(name string, value bool, usage string, typeExp string) *bool {
+	return CommandLine.Bool(name, value, usage, typeExp)
+}
+
+// LenientBoolVar defines a bool flag with specified name, default value, and usage string.
+// The argument p points to a bool variable in which to store the value of the flag.
+// Unlike BoolVar, which accepts the values of strconv.ParseBool, the words
+// yes, y, on, enable and enabled are also accepted for true and no, n, off,
+// disable and disabled for false, in any case, as found in config files.
+func (f *FlagSet) LenientBoolVar(p *bool, name string, value bool, usage string, typeExp string) {
+	f.Var(newLenientBoolValue(value, p), name, usage, typeExp, 1)
+}
+
+// LenientBoolVar defines a bool flag with specified name, default value, and usage string.
+// The argument p points to a bool variable in which to store the value of the flag.
+// Words such as yes/no and on/off are also accepted, see FlagSet.LenientBoolVar.
+func LenientBoolVar(p *bool, name string, value bool, usage string, typeExp string) {
+	CommandLine.Var(newLenientBoolValue(value, p), name, usage, typeExp, 1)
+}
+
+// LenientBool defines a bool flag with specified name, default value, and usage string.
+// The return value is the address of a bool variable that stores the value of the flag.
+// Words such as yes/no and on/off are also accepted, see FlagSet.LenientBoolVar.
+func (f *FlagSet) LenientBool(name string, value bool, usage string, typeExp string) *bool {
+	p := new(bool)
+	f.LenientBoolVar(p, name, value, usage, typeExp)
+	return p
+}
+
+// LenientBool defines a bool flag with specified name, default value, and usage string.
+// The return value is the address of a bool variable that stores the value of the flag.
+// Words such as yes/no and on/off are also accepted, see FlagSet.LenientBoolVar.
+func LenientBool(name string, value bool, usage string, typeExp string) *bool {
+	return CommandLine.LenientBool(name, value, usage, typeExp)
+}
+
+// IntVar defines an int flag with specified name, default value, and usage string.
+// The argument p points to an int variable in which to store the value of the flag.
+func (f *FlagSet) IntVar(p *int, name string, value int, usage string, typeExp string) {
+	f.Var(newIntValue(value, p), name, usage, typeExp, 1)
+}
+
+// IntVar defines an int flag with specified name, default value, and usage string.
+// The argument p points to an int variable in which to store the value of the flag.
+func IntVar(p *int, name string, value int, usage string, typeExp string) {
+	CommandLine.Var(newIntValue(value, p), name, usage, typeExp, 1)
+}
+
+// Int defines an int flag with specified name, default value, and usage string.
+// The return value is the address of an int variable that stores the value of the flag.
+func (f *FlagSet) Int(name string, value int, usage string, typeExp string) *int {
+	p := new(int)
+	f.IntVar(p, name, value, usage, typeExp)
+	return p
+}
+
+// Int defines an int flag with specified name, default value, and usage string.
+// The return value is the address of an int variable that stores the value of the flag.
+func Int(name string, value int, usage string, typeExp string) *int {
+	return CommandLine.Int(name, value, usage, typeExp)
+}
+
+// OptionalIntVar defines an int flag with specified name, default values, and usage string.
+// The argument p points to an int variable in which to store the value of the flag.
+// The variable is absent when the flag is not given, present when the flag
+// is given alone, or the value given.  As the value is optional, it is taken
+// only when attached, as in --compress=9 or -c9, or when the next argument
+// does not start with a "-" and parses as an int.  So "--compress 9" sets 9,
+// while "--compress file" sets present and leaves file as an argument.
+func (f *FlagSet) OptionalIntVar(p *int, name string, present, absent int, usage string, typeExp string) {
+	f.Var(newOptionalIntValue(present, absent, p), name, usage, typeExp, 0)
+}
+
+// OptionalIntVar defines an int flag with specified name, default values, and usage string.
+// The argument p points to an int variable in which to store the value of the flag.
+// The value is optional, see FlagSet.OptionalIntVar.
+func OptionalIntVar(p *int, name string, present, absent int, usage string, typeExp string) {
+	CommandLine.Var(newOptionalIntValue(present, absent, p), name, usage, typeExp, 0)
+}
+
+// OptionalInt defines an int flag with specified name, default values, and usage string.
+// The return value is the address of an int variable that stores the value of the flag.
+// The value is optional, see FlagSet.OptionalIntVar.
+func (f *FlagSet) OptionalInt(name string, present, absent int, usage string, typeExp string) *int {
+	p := new(int)
+	f.OptionalIntVar(p, name, present, absent, usage, typeExp)
+	return p
+}
+
+// OptionalInt defines an int flag with specified name, default values, and usage string.
+// The return value is the address of an int variable that stores the value of the flag.
+// The value is optional, see FlagSet.OptionalIntVar.
+func OptionalInt(name string, present, absent int, usage string, typeExp string) *int {
+	return CommandLine.OptionalInt(name, present, absent, usage, typeExp)
+}
+
+// LogLevelVar defines a log level flag with specified name, default value, and usage string.
+// The argument p points to an int variable in which to store the value of the flag.
+// The value is one of debug, info, warn or error, in any case, which are
+// stored as DebugLevel, InfoLevel, WarnLevel and ErrorLevel in increasing
+// order, so levels can be compared, as in "if level >= WarnLevel".
+func (f *FlagSet) LogLevelVar(p *int, name string, value int, usage string, typeExp string) {
+	f.Var(newLogLevelValue(value, p), name, usage, typeExp, 1)
+}
+
+// LogLevelVar defines a log level flag with specified name, default value, and usage string.
+// The argument p points to an int variable in which to store the value of the flag.
+// The value is one of debug, info, warn or error, see FlagSet.LogLevelVar.
+func LogLevelVar(p *int, name string, value int, usage string, typeExp string) {
+	CommandLine.Var(newLogLevelValue(value, p), name, usage, typeExp, 1)
+}
+
+// LogLevel defines a log level flag with specified name, default value, and usage string.
+// The return value is the address of an int variable that stores the value of the flag.
+// The value is one of debug, info, warn or error, see FlagSet.LogLevelVar.
+func (f *FlagSet) LogLevel(name string, value int, usage string, typeExp string) *int {
+	p := new(int)
+	f.LogLevelVar(p, name, value, usage, typeExp)
+	return p
+}
+
+// LogLevel defines a log level flag with specified name, default value, and usage string.
+// The return value is the address of an int variable that stores the value of the flag.
+// The value is one of debug, info, warn or error, see FlagSet.LogLevelVar.
+func LogLevel(name string, value int, usage string, typeExp string) *int {
+	return CommandLine.LogLevel(name, value, usage, typeExp)
+}
+
+// EnumVar defines a string flag with specified name, default value, and usage string.
+// The argument p points to a string variable in which to store the value of the flag.
+// Unlike StringVar, the value must be one of allowed, matched with case, and
+// the choices are shown in the help.  For other types, see EnumValue.
+func (f *FlagSet) EnumVar(p *string, name string, allowed []string, value string, usage string, typeExp string) {
+	f.Var(&enumStringValue{newStringValue(value, p), allowed}, name, usage, typeExp, 1)
+}
+
+// EnumVar defines a string flag with specified name, default value, and usage string.
+// The argument p points to a string variable in which to store the value of the flag.
+// The value must be one of allowed, see FlagSet.EnumVar.
+func EnumVar(p *string, name string, allowed []string, value string, usage string, typeExp string) {
+	CommandLine.EnumVar(p, name, allowed, value, usage, typeExp)
+}
+
+// IntRangeVar defines an int flag with specified name, default value, and usage string.
+// The argument p points to an int variable in which to store the value of the flag.
+// Unlike IntVar, values outside min to max inclusive are errors, and the range
+// is shown in the help.
+func (f *FlagSet) IntRangeVar(p *int, name string, value, min, max int, usage string, typeExp string) {
+	f.Var(&intRangeValue{newIntValue(value, p), min, max}, name, usage, typeExp, 1)
+}
+
+// IntRangeVar defines an int flag with specified name, default value, and usage string.
+// The argument p points to an int variable in which to store the value of the flag.
+// Values outside min to max inclusive are errors, see FlagSet.IntRangeVar.
+func IntRangeVar(p *int, name string, value, min, max int, usage string, typeExp string) {
+	CommandLine.IntRangeVar(p, name, value, min, max, usage, typeExp)
+}
+
+// Int64RangeVar defines an int64 flag with specified name, default value, and usage string.
+// The argument p points to an int64 variable in which to store the value of the flag.
+// Unlike Int64Var, values outside min to max inclusive are errors, and the
+// range is shown in the help.
+func (f *FlagSet) Int64RangeVar(p *int64, name string, value, min, max int64, usage string, typeExp string) {
+	f.Var(&int64RangeValue{newInt64Value(value, p), min, max}, name, usage, typeExp, 1)
+}
+
+// Int64RangeVar defines an int64 flag with specified name, default value, and usage string.
+// The argument p points to an int64 variable in which to store the value of the flag.
+// Values outside min to max inclusive are errors, see FlagSet.Int64RangeVar.
+func Int64RangeVar(p *int64, name string, value, min, max int64, usage string, typeExp string) {
+	CommandLine.Int64RangeVar(p, name, value, min, max, usage, typeExp)
+}
+
+// UintRangeVar defines a uint flag with specified name, default value, and usage string.
+// The argument p points to a uint variable in which to store the value of the flag.
+// Unlike UintVar, values outside min to max inclusive are errors, and the
+// range is shown in the help.
+func (f *FlagSet) UintRangeVar(p *uint, name string, value, min, max uint, usage string, typeExp string) {
+	f.Var(&uintRangeValue{newUintValue(value, p), min, max}, name, usage, typeExp, 1)
+}
+
+// UintRangeVar defines a uint flag with specified name, default value, and usage string.
+// The argument p points to a uint variable in which to store the value of the flag.
+// Values outside min to max inclusive are errors, see FlagSet.UintRangeVar.
+func UintRangeVar(p *uint, name string, value, min, max uint, usage string, typeExp string) {
+	CommandLine.UintRangeVar(p, name, value, min, max, usage, typeExp)
+}
+
+// Int64Var defines an int64 flag with specified name, default value, and usage string.
+// The argument p points to an int64 variable in which to store the value of the flag.
+func (f *FlagSet) Int64Var(p *int64, name string, value int64, usage string, typeExp string) {
+	f.Var(newInt64Value(value, p), name, usage, typeExp, 1)
+}
+
+// Int64Var defines an int64 flag with specified name, default value, and usage string.
+// The argument p points to an int64 variable in which to store the value of the flag.
+func Int64Var(p *int64, name string, value int64, usage string, typeExp string) {
+	CommandLine.Var(newInt64Value(value, p), name, usage, typeExp, 1)
+}
+
+// Int64 defines an int64 flag with specified name, default value, and usage string.
+// The return value is the address of an int64 variable that stores the value of the flag.
+func (f *FlagSet) Int64(name string, value int64, usage string, typeExp string) *int64 {
+	p := new(int64)
+	f.Int64Var(p, name, value, usage, typeExp)
+	return p
+}
+
+// Int64 defines an int64 flag with specified name, default value, and usage string.
+// The return value is the address of an int64 variable that stores the value of the flag.
+func Int64(name string, value int64, usage string, typeExp string) *int64 {
+	return CommandLine.Int64(name, value, usage, typeExp)
+}
+
+// UintVar defines a uint flag with specified name, default value, and usage string.
+// The argument p points to a uint variable in which to store the value of the flag.
+func (f *FlagSet) UintVar(p *uint, name string, value uint, usage string, typeExp string) {
+	f.Var(newUintValue(value, p), name, usage, typeExp, 1)
+}
+
+// UintVar defines a uint flag with specified name, default value, and usage string.
+// The argument p points to a uint  variable in which to store the value of the flag.
+func UintVar(p *uint, name string, value uint, usage string, typeExp string) {
+	CommandLine.Var(newUintValue(value, p), name, usage, typeExp, 1)
+}
+
+// Uint defines a uint flag with specified name, default value, and usage string.
+// The return value is the address of a uint  variable that stores the value of the flag.
+func (f *FlagSet) Uint(name string, value uint, usage string, typeExp string) *uint {
+	p := new(uint)
+	f.UintVar(p, name, value, usage, typeExp)
+	return p
+}
+
+// Uint defines a uint flag with specified name, default value, and usage string.
+// The return value is the address of a uint  variable that stores the value of the flag.
+func Uint(name string, value uint, usage string, typeExp string) *uint {
+	return CommandLine.Uint(name, value, usage, typeExp)
+}
+
+// Uint64Var defines a uint64 flag with specified name, default value, and usage string.
+// The argument p points to a uint64 variable in which to store the value of the flag.
+func (f *FlagSet) Uint64Var(p *uint64, name string, value uint64, usage string, typeExp string) {
+	f.Var(newUint64Value(value, p), name, usage, typeExp, 1)
+}
+
+// Uint64Var defines a uint64 flag with specified name, default value, and usage string.
+// The argument p points to a uint64 variable in which to store the value of the flag.
+func Uint64Var(p *uint64, name string, value uint64, usage string, typeExp string) {
+	CommandLine.Var(newUint64Value(value, p), name, usage, typeExp, 1)
+}
+
+// Uint64 defines a uint64 flag with specified name, default value, and usage string.
+// The return value is the address of a uint64 variable that stores the value of the flag.
+func (f *FlagSet) Uint64(name string, value uint64, usage string, typeExp string) *uint64 {
+	p := new(uint64)
+	f.Uint64Var(p, name, value, usage, typeExp)
+	return p
+}
+
+// Uint64 defines a uint64 flag with specified name, default value, and usage string.
+// The return value is the address of a uint64 variable that stores the value of the flag.
+func Uint64(name string, value uint64, usage string, typeExp string) *uint64 {
+	return CommandLine.Uint64(name, value, usage, typeExp)
+}
+
+// StringVar defines a string flag with specified name, default value, and usage string.
+// The argument p points to a string variable in which to store the value of the flag.
+func (f *FlagSet) StringVar(p *string, name string, value string, usage string, typeExp string) {
+	f.Var(newStringValue(value, p), name, usage, typeExp, 1)
+}
+
+// StringVar defines a string flag with specified name, default value, and usage string.
+// The argument p points to a string variable in which to store the value of the flag.
+func StringVar(p *string, name string, value string, usage string, typeExp string) {
+	CommandLine.Var(newStringValue(value, p), name, usage, typeExp, 1)
+}
+
+// String defines a string flag with specified name, default value, and usage string.
+// The return value is the address of a string variable that stores the value of the flag.
+func (f *FlagSet) String(name string, value string, usage string, typeExp string) *string {
+	p := new(string)
+	f.StringVar(p, name, value, usage, typeExp)
+	return p
+}
+
+// String defines a string flag with specified name, default value, and usage string.
+// The return value is the address of a string variable that stores the value of the flag.
+func String(name string, value string, usage string, typeExp string) *string {
+	return CommandLine.String(name, value, usage, typeExp)
+}
+
+// StringSliceVar defines a string flag with specified name, default value, and usage string.
+// The argument p points to a string variable in which to store the value of the flag.
+func (f *FlagSet) StringSliceVar(p *([]string), name string, usage string, typeExp string, perFlag int) {
+	if perFlag <= 0 {
+		perFlag = -1
+	}
+	f.Var(newStringSliceValue([]string{}, p), name, usage, typeExp, perFlag)
+}
+
+// StringSliceVar defines a string flag with specified name, default value, and usage string.
+// The argument p points to a string variable in which to store the value of the flag.
+func StringSliceVar(p *([]string), name string, usage string, typeExp string, perFlag int) {
+	if perFlag <= 0 {
+		perFlag = -1
+	}
+	CommandLine.Var(newStringSliceValue([]string{}, p), name, usage, typeExp, perFlag)
+}
+
+// StringSlice defines a string flag with specified name, default value, and usage string.
+// The return value is the address of a string variable that stores the value of the flag.
+func (f *FlagSet) StringSlice(name string, usage string, typeExp string, perFlag int) *[]string {
+	p := new([]string)
+	f.StringSliceVar(p, name, usage, typeExp, perFlag)
+	return p
+}
+
+// StringSlice defines a string flag with specified name, default value, and usage string.
+// The return value is the address of a string variable that stores the value of the flag.
+func StringSlice(name string, usage string, typeExp string, perFlag int) *[]string {
+	return CommandLine.StringSlice(name, usage, typeExp, perFlag)
+}
+
+// IndexedStringSliceVar defines a string slice flag with specified name and usage string.
+// The argument p points to a []string variable in which to store the value of the flag.
+// Unlike StringSliceVar, each value is given after its own flag, and the long
+// name may have an index, as in --item[1] b or --item[1]=b, to set the element
+// at that index, growing the slice as needed.  Without an index, the value is
+// appended.
+func (f *FlagSet) IndexedStringSliceVar(p *([]string), name string, usage string, typeExp string) {
+	f.Var(newIndexedStringSliceValue([]string{}, p), name, usage, typeExp, 1)
+}
+
+// IndexedStringSliceVar defines a string slice flag with specified name and usage string.
+// The argument p points to a []string variable in which to store the value of the flag.
+// An element may be set by index, see FlagSet.IndexedStringSliceVar.
+func IndexedStringSliceVar(p *([]string), name string, usage string, typeExp string) {
+	CommandLine.Var(newIndexedStringSliceValue([]string{}, p), name, usage, typeExp, 1)
+}
+
+// IndexedStringSlice defines a string slice flag with specified name and usage string.
+// The return value is the address of a []string variable that stores the value of the flag.
+// An element may be set by index, see FlagSet.IndexedStringSliceVar.
+func (f *FlagSet) IndexedStringSlice(name string, usage string, typeExp string) *[]string {
+	p := new([]string)
+	f.IndexedStringSliceVar(p, name, usage, typeExp)
+	return p
+}
+
+// IndexedStringSlice defines a string slice flag with specified name and usage string.
+// The return value is the address of a []string variable that stores the value of the flag.
+// An element may be set by index, see FlagSet.IndexedStringSliceVar.
+func IndexedStringSlice(name string, usage string, typeExp string) *[]string {
+	return CommandLine.IndexedStringSlice(name, usage, typeExp)
+}
+
+// IntSliceVar defines an int slice flag with specified name and usage string.
+// The argument p points to a []int variable in which to store the value of the flag.
+func (f *FlagSet) IntSliceVar(p *([]int), name string, usage string, typeExp string, perFlag int) {
+	if perFlag <= 0 {
+		perFlag = -1
+	}
+	f.Var(newIntSliceValue([]int{}, p), name, usage, typeExp, perFlag)
+}
+
+// IntSliceVar defines an int slice flag with specified name and usage string.
+// The argument p points to a []int variable in which to store the value of the flag.
+func IntSliceVar(p *([]int), name string, usage string, typeExp string, perFlag int) {
+	if perFlag <= 0 {
+		perFlag = -1
+	}
+	CommandLine.Var(newIntSliceValue([]int{}, p), name, usage, typeExp, perFlag)
+}
+
+// IntSlice defines an int slice flag with specified name and usage string.
+// The return value is the address of a []int variable that stores the value of the flag.
+func (f *FlagSet) IntSlice(name string, usage string, typeExp string, perFlag int) *[]int {
+	p := new([]int)
+	f.IntSliceVar(p, name, usage, typeExp, perFlag)
+	return p
+}
+
+// IntSlice defines an int slice flag with specified name and usage string.
+// The return value is the address of a []int variable that stores the value of the flag.
+func IntSlice(name string, usage string, typeExp string, perFlag int) *[]int {
+	return CommandLine.IntSlice(name, usage, typeExp, perFlag)
+}
+
+// Float64SliceVar defines a float64 slice flag with specified name and usage string.
+// The argument p points to a []float64 variable in which to store the value of the flag.
+func (f *FlagSet) Float64SliceVar(p *([]float64), name string, usage string, typeExp string, perFlag int) {
+	if perFlag <= 0 {
+		perFlag = -1
+	}
+	f.Var(newFloat64SliceValue([]float64{}, p), name, usage, typeExp, perFlag)
+}
+
+// Float64SliceVar defines a float64 slice flag with specified name and usage string.
+// The argument p points to a []float64 variable in which to store the value of the flag.
+func Float64SliceVar(p *([]float64), name string, usage string, typeExp string, perFlag int) {
+	if perFlag <= 0 {
+		perFlag = -1
+	}
+	CommandLine.Var(newFloat64SliceValue([]float64{}, p), name, usage, typeExp, perFlag)
+}
+
+// Float64Slice defines a float64 slice flag with specified name and usage string.
+// The return value is the address of a []float64 variable that stores the value of the flag.
+func (f *FlagSet) Float64Slice(name string, usage string, typeExp string, perFlag int) *[]float64 {
+	p := new([]float64)
+	f.Float64SliceVar(p, name, usage, typeExp, perFlag)
+	return p
+}
+
+// Float64Slice defines a float64 slice flag with specified name and usage string.
+// The return value is the address of a []float64 variable that stores the value of the flag.
+func Float64Slice(name string, usage string, typeExp string, perFlag int) *[]float64 {
+	return CommandLine.Float64Slice(name, usage, typeExp, perFlag)
+}
+
+// DurationSliceVar defines a time.Duration slice flag with specified name and usage string.
+// The argument p points to a []time.Duration variable in which to store the value of the flag.
+func (f *FlagSet) DurationSliceVar(p *([]time.Duration), name string, usage string, typeExp string, perFlag int) {
+	if perFlag <= 0 {
+		perFlag = -1
+	}
+	f.Var(newDurationSliceValue([]time.Duration{}, p), name, usage, typeExp, perFlag)
+}
+
+// DurationSliceVar defines a time.Duration slice flag with specified name and usage string.
+// The argument p points to a []time.Duration variable in which to store the value of the flag.
+func DurationSliceVar(p *([]time.Duration), name string, usage string, typeExp string, perFlag int) {
+	if perFlag <= 0 {
+		perFlag = -1
+	}
+	CommandLine.Var(newDurationSliceValue([]time.Duration{}, p), name, usage, typeExp, perFlag)
+}
+
+// DurationSlice defines a time.Duration slice flag with specified name and usage string.
+// The return value is the address of a []time.Duration variable that stores the value of the flag.
+func (f *FlagSet) DurationSlice(name string, usage string, typeExp string, perFlag int) *[]time.Duration {
+	p := new([]time.Duration)
+	f.DurationSliceVar(p, name, usage, typeExp, perFlag)
+	return p
+}
+
+// DurationSlice defines a time.Duration slice flag with specified name and usage string.
+// The return value is the address of a []time.Duration variable that stores the value of the flag.
+func DurationSlice(name string, usage string, typeExp string, perFlag int) *[]time.Duration {
+	return CommandLine.DurationSlice(name, usage, typeExp, perFlag)
+}
+
+// StringMapVar defines a key=value flag with specified name and usage string.
+// The argument p points to a map[string]string variable in which to store the
+// value of the flag.  Each pair given is added to the map, and when a key is
+// given more than once the last value wins.
+func (f *FlagSet) StringMapVar(p *map[string]string, name string, usage string, typeExp string, perFlag int) {
+	if perFlag <= 0 {
+		perFlag = -1
+	}
+	f.Var(newStringMapValue(map[string]string{}, p), name, usage, typeExp, perFlag)
+}
+
+// StringMapVar defines a key=value flag with specified name and usage string.
+// The argument p points to a map[string]string variable in which to store the
+// value of the flag.
+func StringMapVar(p *map[string]string, name string, usage string, typeExp string, perFlag int) {
+	if perFlag <= 0 {
+		perFlag = -1
+	}
+	CommandLine.Var(newStringMapValue(map[string]string{}, p), name, usage, typeExp, perFlag)
+}
+
+// StringMap defines a key=value flag with specified name and usage string.
+// The return value is the address of a map[string]string variable that stores
+// the value of the flag.
+func (f *FlagSet) StringMap(name string, usage string, typeExp string, perFlag int) *map[string]string {
+	p := new(map[string]string)
+	f.StringMapVar(p, name, usage, typeExp, perFlag)
+	return p
+}
+
+// StringMap defines a key=value flag with specified name and usage string.
+// The return value is the address of a map[string]string variable that stores
+// the value of the flag.
+func StringMap(name string, usage string, typeExp string, perFlag int) *map[string]string {
+	return CommandLine.StringMap(name, usage, typeExp, perFlag)
+}
+
+// ExistingFileVar defines a string flag with specified name, default value, and usage string.
+// The argument p points to a string variable in which to store the value of the flag.
+// The value must be the path of an existing file, which is not a directory.
+func (f *FlagSet) ExistingFileVar(p *string, name string, value string, usage string, typeExp string) {
+	f.Var(newPathValue(value, p, checkExistingFile), name, usage, typeExp, 1)
+}
+
+// ExistingFileVar defines a string flag with specified name, default value, and usage string.
+// The argument p points to a string variable in which to store the value of the flag.
+// The value must be the path of an existing file, which is not a directory.
+func ExistingFileVar(p *string, name string, value string, usage string, typeExp string) {
+	CommandLine.Var(newPathValue(value, p, checkExistingFile), name, usage, typeExp, 1)
+}
+
+// ExistingDirVar defines a string flag with specified name, default value, and usage string.
+// The argument p points to a string variable in which to store the value of the flag.
+// The value must be the path of an existing directory.
+func (f *FlagSet) ExistingDirVar(p *string, name string, value string, usage string, typeExp string) {
+	f.Var(newPathValue(value, p, checkExistingDir), name, usage, typeExp, 1)
+}
+
+// ExistingDirVar defines a string flag with specified name, default value, and usage string.
+// The argument p points to a string variable in which to store the value of the flag.
+// The value must be the path of an existing directory.
+func ExistingDirVar(p *string, name string, value string, usage string, typeExp string) {
+	CommandLine.Var(newPathValue(value, p, checkExistingDir), name, usage, typeExp, 1)
+}
+
+// NewFileVar defines a string flag with specified name, default value, and usage string.
+// The argument p points to a string variable in which to store the value of the flag.
+// The value must be a path which can be created, that is the parent directory
+// must exist and the path must not be a directory.
+func (f *FlagSet) NewFileVar(p *string, name string, value string, usage string, typeExp string) {
+	f.Var(newPathValue(value, p, checkNewFile), name, usage, typeExp, 1)
+}
+
+// NewFileVar defines a string flag with specified name, default value, and usage string.
+// The argument p points to a string variable in which to store the value of the flag.
+// The value must be a path which can be created, that is the parent directory
+// must exist and the path must not be a directory.
+func NewFileVar(p *string, name string, value string, usage string, typeExp string) {
+	CommandLine.Var(newPathValue(value, p, checkNewFile), name, usage, typeExp, 1)
+}
+
+// StringListVar defines a string list flag with specified name and usage string.
+// The argument p points to a string slice variable in which to store the values of the flag.
+// All the arguments following the flag, up to the next one starting with a
+// "-", are consumed and each is further split on commas.  Spaces around each
+// item are trimmed and empty items are dropped, so "--tags a,b c" and
+// "--tags a, b --tags c" both give [a b c].
+func (f *FlagSet) StringListVar(p *([]string), name string, usage string, typeExp string) {
+	f.Var(newStringListValue([]string{}, p), name, usage, typeExp, -1)
+}
+
+// StringListVar defines a string list flag with specified name and usage string.
+// The argument p points to a string slice variable in which to store the values of the flag.
+// Each argument is further split on commas, see FlagSet.StringListVar.
+func StringListVar(p *([]string), name string, usage string, typeExp string) {
+	CommandLine.Var(newStringListValue([]string{}, p), name, usage, typeExp, -1)
+}
+
+// StringList defines a string list flag with specified name and usage string.
+// The return value is the address of a string slice variable that stores the values of the flag.
+// Each argument is further split on commas, see FlagSet.StringListVar.
+func (f *FlagSet) StringList(name string, usage string, typeExp string) *[]string {
+	p := new([]string)
+	f.StringListVar(p, name, usage, typeExp)
+	return p
+}
+
+// StringList defines a string list flag with specified name and usage string.
+// The return value is the address of a string slice variable that stores the values of the flag.
+// Each argument is further split on commas, see FlagSet.StringListVar.
+func StringList(name string, usage string, typeExp string) *[]string {
+	return CommandLine.StringList(name, usage, typeExp)
+}
+
+// Float64Var defines a float64 flag with specified name, default value, and usage string.
+// The argument p points to a float64 variable in which to store the value of the flag.
+func (f *FlagSet) Float64Var(p *float64, name string, value float64, usage string, typeExp string) {
+	f.Var(newFloat64Value(value, p), name, usage, typeExp, 1)
+}
+
+// Float64Var defines a float64 flag with specified name, default value, and usage string.
+// The argument p points to a float64 variable in which to store the value of the flag.
+func Float64Var(p *float64, name string, value float64, usage string, typeExp string) {
+	CommandLine.Var(newFloat64Value(value, p), name, usage, typeExp, 1)
+}
+
+// Float64 defines a float64 flag with specified name, default value, and usage string.
+// The return value is the address of a float64 variable that stores the value of the flag.
+func (f *FlagSet) Float64(name string, value float64, usage string, typeExp string) *float64 {
+	p := new(float64)
+	f.Float64Var(p, name, value, usage, typeExp)
+	return p
+}
+
+// Float64 defines a float64 flag with specified name, default value, and usage string.
+// The return value is the address of a float64 variable that stores the value of the flag.
+func Float64(name string, value float64, usage string, typeExp string) *float64 {
+	return CommandLine.Float64(name, value, usage, typeExp)
+}
+
+// UnitFloatVar defines a float64 flag with specified name, default value, and usage string.
+// The argument p points to a float64 variable in which to store the value of the flag.
+// The value may end in one of the units, which maps each unit to the number
+// to multiply by to convert into the base unit, and a value without a unit is
+// multiplied by base.  So with units of {"m": 1, "km": 1000}, a value of
+// "5km" stores 5000.  The default value is in the base unit.
+func (f *FlagSet) UnitFloatVar(p *float64, name string, units map[string]float64, base, value float64, usage string, typeExp string) {
+	f.Var(newUnitFloatValue(value, p, units, base), name, usage, typeExp, 1)
+}
+
+// UnitFloatVar defines a float64 flag with specified name, default value, and usage string.
+// The argument p points to a float64 variable in which to store the value of the flag.
+// The value may end in one of the units, see FlagSet.UnitFloatVar.
+func UnitFloatVar(p *float64, name string, units map[string]float64, base, value float64, usage string, typeExp string) {
+	CommandLine.UnitFloatVar(p, name, units, base, value, usage, typeExp)
+}
+
+// DurationVar defines a time.Duration flag with specified name, default value, and usage string.
+// The argument p points to a time.Duration variable in which to store the value of the flag.
+func (f *FlagSet) DurationVar(p *time.Duration, name string, value time.Duration, usage string, typeExp string) {
+	f.Var(newDurationValue(value, p), name, usage, typeExp, 1)
+}
+
+// DurationVar defines a time.Duration flag with specified name, default value, and usage string.
+// The argument p points to a time.Duration variable in which to store the value of the flag.
+func DurationVar(p *time.Duration, name string, value time.Duration, usage string, typeExp string) {
+	CommandLine.Var(newDurationValue(value, p), name, usage, typeExp, 1)
+}
+
+// Duration defines a time.Duration flag with specified name, default value, and usage string.
+// The return value is the address of a time.Duration variable that stores the value of the flag.
+func (f *FlagSet) Duration(name string, value time.Duration, usage string, typeExp string) *time.Duration {
+	p := new(time.Duration)
+	f.DurationVar(p, name, value, usage, typeExp)
+	return p
+}
+
+// Duration defines a time.Duration flag with specified name, default value, and usage string.
+// The return value is the address of a time.Duration variable that stores the value of the flag.
+func Duration(name string, value time.Duration, usage string, typeExp string) *time.Duration {
+	return CommandLine.Duration(name, value, usage, typeExp)
+}
+
+// DurationZeroVar defines a time.Duration flag with specified name, default value, and usage string.
+// The argument p points to a time.Duration variable in which to store the value of the flag.
+// Unlike DurationVar, a zero default is shown in the help as zeroDisplay, such
+// as "none" or "0", rather than "0s".  Other defaults are shown as usual.
+func (f *FlagSet) DurationZeroVar(p *time.Duration, name string, value time.Duration, zeroDisplay, usage string, typeExp string) {
+	f.Var(&zeroDurationValue{newDurationValue(value, p), zeroDisplay}, name, usage, typeExp, 1)
+}
+
+// DurationZeroVar defines a time.Duration flag with specified name, default value, and usage string.
+// The argument p points to a time.Duration variable in which to store the value of the flag.
+// A zero default is shown in the help as zeroDisplay, see FlagSet.DurationZeroVar.
+func DurationZeroVar(p *time.Duration, name string, value time.Duration, zeroDisplay, usage string, typeExp string) {
+	CommandLine.DurationZeroVar(p, name, value, zeroDisplay, usage, typeExp)
+}
+
+// DurationUnitsVar defines a time.Duration flag with specified name, default value, and usage string.
+// The argument p points to a time.Duration variable in which to store the value of the flag.
+// Unlike DurationVar, the value may only use the units in allowedUnits, so
+// with []string{"s", "m", "h"} a value of "100ms" is an error.  This keeps
+// users from giving more precision than the program can use.
+func (f *FlagSet) DurationUnitsVar(p *time.Duration, name string, value time.Duration, allowedUnits []string, usage string, typeExp string) {
+	f.Var(&unitDurationValue{newDurationValue(value, p), allowedUnits}, name, usage, typeExp, 1)
+}
+
+// DurationUnitsVar defines a time.Duration flag with specified name, default value, and usage string.
+// The argument p points to a time.Duration variable in which to store the value of the flag.
+// The value may only use the units in allowedUnits, see FlagSet.DurationUnitsVar.
+func DurationUnitsVar(p *time.Duration, name string, value time.Duration, allowedUnits []string, usage string, typeExp string) {
+	CommandLine.DurationUnitsVar(p, name, value, allowedUnits, usage, typeExp)
+}
+
+// DurationKeywordsVar defines a time.Duration flag with specified name, default value, and usage string.
+// The argument p points to a time.Duration variable in which to store the value of the flag.
+// Unlike DurationVar, the keywords are also accepted for the durations they
+// map to, such as "never" for 0 and "forever" for math.MaxInt64, and the value
+// is shown as its keyword when it has one.
+func (f *FlagSet) DurationKeywordsVar(p *time.Duration, name string, value time.Duration, keywords map[string]time.Duration, usage string, typeExp string) {
+	f.Var(&keywordDurationValue{newDurationValue(value, p), keywords}, name, usage, typeExp, 1)
+}
+
+// DurationKeywordsVar defines a time.Duration flag with specified name, default value, and usage string.
+// The argument p points to a time.Duration variable in which to store the value of the flag.
+// The keywords are also accepted, see FlagSet.DurationKeywordsVar.
+func DurationKeywordsVar(p *time.Duration, name string, value time.Duration, keywords map[string]time.Duration, usage string, typeExp string) {
+	CommandLine.DurationKeywordsVar(p, name, value, keywords, usage, typeExp)
+}
+
+// ISODurationVar defines a time.Duration flag with specified name, default value, and usage string.
+// The argument p points to a time.Duration variable in which to store the value of the flag.
+// Unlike DurationVar, ISO-8601 durations such as "PT1H30M" are also accepted.
+func (f *FlagSet) ISODurationVar(p *time.Duration, name string, value time.Duration, usage string, typeExp string) {
+	f.Var(newISODurationValue(value, p), name, usage, typeExp, 1)
+}
+
+// ISODurationVar defines a time.Duration flag with specified name, default value, and usage string.
+// The argument p points to a time.Duration variable in which to store the value of the flag.
+// Unlike DurationVar, ISO-8601 durations such as "PT1H30M" are also accepted.
+func ISODurationVar(p *time.Duration, name string, value time.Duration, usage string, typeExp string) {
+	CommandLine.Var(newISODurationValue(value, p), name, usage, typeExp, 1)
+}
+
+// ISODuration defines a time.Duration flag with specified name, default value, and usage string.
+// The return value is the address of a time.Duration variable that stores the value of the flag.
+// Unlike Duration, ISO-8601 durations such as "PT1H30M" are also accepted.
+func (f *FlagSet) ISODuration(name string, value time.Duration, usage string, typeExp string) *time.Duration {
+	p := new(time.Duration)
+	f.ISODurationVar(p, name, value, usage, typeExp)
+	return p
+}
+
+// ISODuration defines a time.Duration flag with specified name, default value, and usage string.
+// The return value is the address of a time.Duration variable that stores the value of the flag.
+// Unlike Duration, ISO-8601 durations such as "PT1H30M" are also accepted.
+func ISODuration(name string, value time.Duration, usage string, typeExp string) *time.Duration {
+	return CommandLine.ISODuration(name, value, usage, typeExp)
+}
+
+// FlagFunc defines a flag with the specified name and usage string.
+// Each time the flag is seen, fn is called with the value of the flag.
+// If fn returns a non-nil error, it will be treated as a flag value parsing error.
+// As a Getter, the flag's Value returns the []string last handed to fn without
+// error, or nil if fn has not been called.
+func (f *FlagSet) FlagFunc(name, usage string, typeExp string, argsNeeded int, fn func([]string) error) {
+	f.Var(&flagFuncValue{fn: fn}, name, usage, typeExp, argsNeeded)
+}
+
+// FlagFunc defines a flag with the specified name and usage string.
+// Each time the flag is seen, fn is called with the value of the flag.
+// If fn returns a non-nil error, it will be treated as a flag value parsing error.
+func FlagFunc(name, usage string, typeExp string, argsNeeded int, fn func([]string) error) {
+	CommandLine.FlagFunc(name, usage, typeExp, argsNeeded, fn)
+}
+
+// FlagFuncDefault defines a flag with the specified name and usage string,
+// like FlagFunc, with defaultDisplay shown as the default in the help, such as
+// "none" for (Default: none).
+func (f *FlagSet) FlagFuncDefault(name, usage string, typeExp string, argsNeeded int, defaultDisplay string, fn func([]string) error) {
+	f.Var(&flagFuncValue{fn: fn, display: defaultDisplay}, name, usage, typeExp, argsNeeded)
+}
+
+// FlagFuncDefault defines a flag with the specified name and usage string,
+// like FlagFunc, with defaultDisplay shown as the default in the help.
+func FlagFuncDefault(name, usage string, typeExp string, argsNeeded int, defaultDisplay string, fn func([]string) error) {
+	CommandLine.FlagFuncDefault(name, usage, typeExp, argsNeeded, defaultDisplay, fn)
+}
+
+// FlagFuncIndexed defines a flag with the specified name and usage string.
+// Each time the flag is seen, fn is called with the occurrence index, counting
+// from 0, and the argsNeeded values which followed the flag.  This keeps the
+// ordering when building up a list from a repeated flag.
+// If fn returns a non-nil error, it will be treated as a flag value parsing error.
+func (f *FlagSet) FlagFuncIndexed(name, usage string, typeExp string, argsNeeded int, fn func(i int, vals []string) error) {
+	f.Var(&flagFuncIndexedValue{fn: fn}, name, usage, typeExp, argsNeeded)
+}
+
+// FlagFuncIndexed defines a flag with the specified name and usage string.
+// Each time the flag is seen, fn is called with the occurrence index, counting
+// from 0, and the argsNeeded values which followed the flag.
+// If fn returns a non-nil error, it will be treated as a flag value parsing error.
+func FlagFuncIndexed(name, usage string, typeExp string, argsNeeded int, fn func(i int, vals []string) error) {
+	CommandLine.FlagFuncIndexed(name, usage, typeExp, argsNeeded, fn)
+}
+
+// EnforceNamePolicy sets policy to check the name of each flag as it is
+// defined, and checks the flags already defined.  A name for which policy
+// returns an error panics, as for a flag defined twice, which keeps the names
+// in a large program consistent.  KebabCase is one such policy.
+func (f *FlagSet) EnforceNamePolicy(policy func(name string) error) {
+	f.namePolicy = policy
+	for _, flag := range f.formal {
+		for _, name := range flag.Name {
+			f.checkName(name)
+		}
+	}
+}
+
+// EnforceNamePolicy sets policy to check the name of each command-line flag,
+// see FlagSet.EnforceNamePolicy.
+func EnforceNamePolicy(policy func(name string) error) {
+	CommandLine.EnforceNamePolicy(policy)
+}
+
+// checkName panics if the name does not meet the policy set with
+// EnforceNamePolicy.
+func (f *FlagSet) checkName(name string) {
+	if f.namePolicy == nil {
+		return
+	}
+	if err := f.namePolicy(name); err != nil {
+		fmt.Fprintf(f.ErrorOutput(), "%s %v %s does not meet the naming policy: %v\n", f.name, f.FlagKnownAs, name, err)
+		panic(fmt.Sprintf("%v name policy", f.FlagKnownAs)) // Happens only if flags are declared with bad names
+	}
+}
+
+// KebabCase is a policy for EnforceNamePolicy allowing short names of a
+// single letter or digit, and long names of lower case words joined by
+// dashes, such as "dry-run".
+func KebabCase(name string) error {
+	if rlen(name) == 1 {
+		r, _ := utf8.DecodeRuneInString(name)
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return errors.New("a short name must be a letter or digit")
+		}
+		return nil
+	}
+	for _, word := range strings.Split(name, "-") {
+		if word == "" {
+			return errors.New("a long name must not have empty words")
+		}
+		for _, r := range word {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9') {
+				return errors.New("a long name must be lower case words joined by dashes")
+			}
+		}
+	}
+	return nil
+}
+
+// Var defines a flag with the specified name and usage string. The type and
+// value of the flag are represented by the first argument, of type Value, which
+// typically holds a user-defined implementation of Value. For instance, the
+// caller could create a flag that turns a comma-separated string into a slice
+// of strings by giving the slice the methods of Value; in particular, Set would
+// decompose the comma-separated string into the slice.
+func (f *FlagSet) Var(value Value, flagStr string, usage string, typeExp string, args int) {
+	names := splitOn(flagStr, ' ', -1)
+
+	// Make sure the single char is second, if there is one
+	if len(names) > 1 { // TODO: fix for more than two
+		if rlen(names[0]) == 1 && rlen(names[1]) > 1 {
+			names[0], names[1] = names[1], names[0]
+		}
+	}
+
+	// Remember the default value as a string; it won't change.
+	defValue := value.String()
+	if d, ok := value.(DefaultStringer); ok {
+		defValue = d.DefaultString()
+	}
+	flag := &Flag{
+		Name:         names,
+		Usage:        usage,
+		Value:        value,
+		DefValue:     defValue,
+		TypeExpected: typeExp,
+		ArgsNeeded:   args,
+		Grouping:     f.curGrouping,
+	}
+
+	// Check the names against the policy, and if the flag exists already
+	f.checkFrozen()
+	for _, name := range names {
+		f.checkName(name)
+		alreadythere := f.Lookup(name)
+		if alreadythere != nil {
+			fmt.Fprintf(f.ErrorOutput(), "%s %v redefined: %s\n", f.name, f.FlagKnownAs, name)
+			panic(fmt.Sprintf("%v redefinition", f.FlagKnownAs)) // Happens only if flags are declared with identical names
+		}
+	}
+
+	// Go ahead and add this new flag
+	if f.formal == nil {
+		f.formal = make([]*Flag, 0)
+	}
+	f.formal = append(f.formal, flag)
+}
+
+// Var defines a flag with the specified name and usage string. The type and
+// value of the flag are represented by the first argument, of type Value, which
+// typically holds a user-defined implementation of Value. For instance, the
+// caller could create a flag that turns a comma-separated string into a slice
+// of strings by giving the slice the methods of Value; in particular, Set would
+// decompose the comma-separated string into the slice.
+func Var(value Value, name string, usage string, typeExp string, argsNeeded int) {
+	CommandLine.Var(value, name, usage, typeExp, argsNeeded)
+}
+
+// failf prints to standard error a formatted error and usage message and
+// returns the error.
+func (f *FlagSet) failf(format string, a ...interface{}) error {
+	return f.fail(fmt.Errorf(format, a...))
+}
+
+// failFlag is like failf, but returns pe with the formatted message, for
+// errors about a flag.
+func (f *FlagSet) failFlag(pe *ParseError, format string, a ...interface{}) error {
+	pe.msg = fmt.Sprintf(format, a...)
+	return f.fail(pe)
+}
+
+// fail prints to standard error the error and usage message and returns the
+// error.
+func (f *FlagSet) fail(err error) error {
+	if f.echoArgsOnError {
+		cmd := []string{f.progName()}
+		for _, arg := range f.rawArgs {
+			if arg == "" || strings.ContainsAny(arg, " \t\n\"'") {
+				arg = strconv.Quote(arg)
+			}
+			cmd = append(cmd, arg)
+		}
+		fmt.Fprintf(f.ErrorOutput(), "while parsing: %s\n", strings.Join(cmd, " "))
+	}
+	fmt.Fprintln(f.ErrorOutput(), err)
+	f.usage()
+	return err
+}
+
+// usage calls the Usage method for the flag set, or the usage function if
+// the flag set is CommandLine.
+func (f *FlagSet) usage() {
+	if f.Usage == nil {
+		if f == CommandLine {
+			Usage()
+		} else {
+			defaultUsage(f)
+		}
+	} else {
+		f.Usage()
+	}
+}
+
+// fill pulls arguments from the source given to ParseFunc, if any, until at
+// least n are waiting to be processed, and reports whether there are.
+func (f *FlagSet) fill(n int) bool {
+	for len(f.procArgs) < n && f.procNext != nil {
+		a, ok := f.procNext()
+		if !ok {
+			f.procNext = nil
+			break
+		}
+		f.procArgs = append(f.procArgs, a)
+		f.rawArgs = append(f.rawArgs, a)
+	}
+	return len(f.procArgs) >= n
+}
+
+// drain pulls all the arguments left in the source given to ParseFunc, or
+// only one more than SetMaxArgs allows, as they are all taken as arguments.
+func (f *FlagSet) drain() {
+	for f.procNext != nil && (!f.argLimit || len(f.args)+len(f.procArgs) <= f.maxArgs) {
+		f.fill(len(f.procArgs) + 1)
+	}
+}
+
+func (f *FlagSet) parseOne() (flagName string, long, finished bool, err error) {
+	if f.procFlag == "" && !f.fill(1) {
+		finished = true
+		return
+	}
+
+	// processing previously encountered single-rune flag
+	if flag := f.procFlag; len(flag) > 0 {
+		_, n := utf8.DecodeRuneInString(flag)
+		f.procFlag = flag[n:]
+		flagName = flag[0:n]
+		return
+	}
+
+	a := f.procArgs[0]
+
+	// one non-flag argument
+	if a == "-" || a == "" || a[0] != '-' {
+		if f.stopAtOperand {
+			finished = true
+			return
+		}
+		if f.allowIntersperse {
+			f.args = append(f.args, a)
+			f.procArgs = f.procArgs[1:]
+			err = f.checkMaxArgs()
+			return
+		}
+		f.drain()
+		f.args = append(f.args, f.procArgs...)
+		f.procArgs = nil
+		finished = true
+		err = f.checkMaxArgs()
+		return
+	}
+
+	// end of flags
+	if f.procArgs[0] == "--" {
+		if f.stopAtOperand {
+			f.procArgs = f.procArgs[1:]
+			finished = true
+			return
+		}
+		f.drain()
+		if f.passthrough != nil {
+			*f.passthrough = append([]string{}, f.procArgs[1:]...)
+			f.procArgs = nil
+			finished = true
+			return
+		}
+		f.args = append(f.args, f.procArgs[1:]...)
+		f.procArgs = nil
+		finished = true
+		err = f.checkMaxArgs()
+		return
+	}
+
+	// a long flag, an expansion or some number of single-rune flags
+	flagName, f.procFlag, long, f.procAttached = f.splitFlagArg(a)
+	f.procArgs = f.procArgs[1:]
+	if flagName == "" {
+		err = fmt.Errorf("empty %v in argument %q", f.FlagKnownAs, a)
+	}
+	return
+}
+
+// splitFlagArg splits the argument a, which starts with a dash, into the name
+// of the first flag it gives and what follows the name, either the value
+// attached with "=" or the rest of a cluster of single-rune flags.  It
+// reports whether the name is long and whether the rest was attached.
+func (f *FlagSet) splitFlagArg(a string) (name, rest string, long, attached bool) {
+	// long flag signified with "--" prefix
+	if a[1] == '-' {
+		if parts := strings.SplitN(a[2:], "=", 2); len(parts) > 1 {
+			return parts[0], parts[1], true, true
+		}
+		return a[2:], "", true, false
+	}
+
+	// some number of single-rune flags
+	a = a[1:]
+	if _, ok := f.expansions[a]; ok {
+		return a, "", false, false
+	}
+	if f.singleDashLong {
+		parts := strings.SplitN(a, "=", 2)
+		if rlen(parts[0]) > 1 && f.lookupArg(parts[0]) != nil {
+			if len(parts) > 1 {
+				return parts[0], parts[1], true, true
+			}
+			return parts[0], "", true, false
+		}
+	}
+	_, n := utf8.DecodeRuneInString(a)
+	if len(a) > n && a[n] == '=' {
+		return a[:n], a[n+1:], false, true
+	}
+	return a[:n], a[n:], false, false
+}
+
+// lookupArg returns the flag set by the name given in an argument, which for
+// an indexed name, such as item[1], is the indexed flag item.
+func (f *FlagSet) lookupArg(name string) *Flag {
+	flag := f.Lookup(name)
+	if base, _, ok := splitIndex(name); flag == nil && ok {
+		// an indexed name, such as item[1], sets an element of an indexed flag
+		if b := f.Lookup(base); b != nil {
+			if i, ok := b.Value.(indexedFlag); ok && i.IsIndexed() {
+				flag = b
+			}
+		}
+	}
+	return flag
+}
+
+// IsStdinDash reports whether s is the lone "-" argument, which by convention
+// means standard input, or standard output when used for an output file.
+func IsStdinDash(s string) bool {
+	return s == "-"
+}
+
+func contains(a []string, b []string) bool {
+	found := false
+contains_outer:
+	for _, B := range a {
+		found = false
+		for _, A := range a {
+			if A == B {
+				found = true
+				continue contains_outer
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return found
+}
+
+func flagWithMinus(name string) string {
+	if rlen(name) > 1 {
+		return "--" + name
+	}
+	return "-" + name
+}
+
+// isClearToken reports whether v is the clear token for the flag, see
+// SetClearToken.
+func (f *FlagSet) isClearToken(flag *Flag, v string) bool {
+	_, ok := baseValue(flag.Value).(clearable)
+	return ok && f.clearTokenSet && v == f.clearToken
+}
+
+// clearValue empties the value of the flag when the first of vals is the
+// clear token, see SetClearToken, or starts with "=" when the mutation syntax
+// is on, see SetSliceMutationSyntax, and returns the rest of vals.  It reports
+// whether the first value was such a token, so if the rest is empty there is
+// nothing more to set.
+func (f *FlagSet) clearValue(flag *Flag, vals []string) (rest []string, token bool) {
+	if _, ok := baseValue(flag.Value).(clearable); !ok || len(vals) == 0 {
+		return vals, false
+	}
+	c := flag.Value.(clearable)
+	if f.isClearToken(flag, vals[0]) {
+		c.Clear()
+		return vals[1:], true
+	}
+	if f.sliceMutation && (strings.HasPrefix(vals[0], "=") || strings.HasPrefix(vals[0], "+")) {
+		if vals[0][0] == '=' {
+			c.Clear()
+		}
+		if vals[0] == "=" || vals[0] == "+" {
+			return vals[1:], true
+		}
+		return append([]string{vals[0][1:]}, vals[1:]...), true
+	}
+	return vals, false
+}
+
+func (f *FlagSet) parseFlagArg(name string, long bool) (finished bool, err error) {
+	attached := f.procAttached
+	f.procAttached = false
+	if exp, ok := f.expansions[name]; ok {
+		if (f.procFlag != "" || attached) && long {
+			found := f.procFlag
+			f.procFlag = ""
+			return false, f.failFlag(&ParseError{Name: name, Value: []string{found}, Reason: ReasonUnwanted},
+				"%v unwanted argument %q found after: %s", f.FlagKnownAs, found, flagWithMinus(name))
+		}
+		f.procArgs = append(append([]string{}, exp...), f.procArgs...)
+		return
+	}
+	flag := f.lookupArg(name)
+	if flag == nil {
+		if (name == "help" || name == "h") && !f.noBuiltinHelp { // special case for nice help message.
+			f.helpRequested = true
+			f.usage()
+			return false, ErrHelp
+		}
+		if name == "get-bash-completion" {
+			if contains(os.Environ(),
+				[]string{"COMP_TYPE", "COMP_LINE", "COMP_POINT", "COMP_KEY"}) {
+				if len(os.Args) >= 3 {
+					if len(os.Args[3]) > 0 && strings.HasPrefix("-", os.Args[3]) {
+						fmt.Println("--")
+					}
+					for _, flag := range f.formal {
+						if flag.Hidden {
+							continue
+						}
+						for _, name := range flag.Name {
+							if len(os.Args[3]) > 0 && strings.HasPrefix(flagWithMinus(name), os.Args[3]) {
+								fmt.Println(flagWithMinus(name))
+							}
+						}
+					}
+				}
+			}
+			os.Exit(0)
+			//fmt.Fprintf(os.Stderr, "go\nmake\nmy day\n")
+			//fmt.Printf("makeme\n")
+			fmt.Printf("&&go -- test\nmake -- my\nmy day -- plac3\n")
+			fmt.Fprintf(os.Stderr, "%#v\n", os.Args)
+			for _, e := range os.Environ() {
+				if strings.HasPrefix(e, "COM") {
+					pair := strings.SplitN(e, "=", 2)
+					fmt.Fprintf(os.Stderr, "  %v=%q\n", pair[0], pair[1])
+				}
+			}
+		}
+		// Print --xxx when flag is more than one rune.
+		return false, f.failFlag(&ParseError{Name: name, Reason: ReasonUndefined},
+			"%v provided but not defined: %s", f.FlagKnownAs, flagWithMinus(name))
+	}
+	var vals []string // values handed to flag.Value.Set
+	if a, ok := f.aliases[name]; ok {
+		f.warnDeprecated(name, a)
+	}
+	switch flag.ArgsNeeded {
+	case 0:
+		// Param doesn't need an arg, or takes one only if acceptable.
+		vals = []string{}
+		if _, ok := baseValue(flag.Value).(optionalArg); ok {
+			o := flag.Value.(optionalArg)
+			switch {
+			case attached || f.procFlag != "" && (long || o.Accepts(f.procFlag)):
+				vals, f.procFlag = []string{f.procFlag}, ""
+			case f.procFlag == "" && f.fill(1) && !strings.HasPrefix(f.procArgs[0], "-") && o.Accepts(f.procArgs[0]):
+				vals, f.procArgs = []string{f.procArgs[0]}, f.procArgs[1:]
+			}
+			given := vals // as given, without any indirection resolved
+			if vals, err = f.prepareValues(flag, name, vals); err != nil {
+				return false, err
+			}
+			if err := flag.set(name, vals); err != nil {
+				err = f.hideResolved(err, given, vals)
+				pe := &ParseError{Name: name, Value: given, Reason: ReasonInvalid, Err: err}
+				switch {
+				case len(given) == 0:
+					// the flag was given alone
+					return false, f.failFlag(pe, "invalid value for %v %s: %v",
+						f.FlagKnownAs, flagWithMinus(name), err)
+				case given[0] == "":
+					pe.Reason = ReasonEmpty
+					return false, f.failFlag(pe, "empty value for %v %s", f.FlagKnownAs, flagWithMinus(name))
+				}
+				return false, f.failFlag(pe, "invalid value %q for %v %s: %v",
+					given[0], f.FlagKnownAs, flagWithMinus(name), err)
+			}
+			break
+		}
+		if vals, err = f.prepareValues(flag, name, vals); err != nil {
+			return false, err
+		}
+		if err := flag.set(name, vals); err != nil {
+			return false, f.failFlag(&ParseError{Name: name, Value: vals, Reason: ReasonInvalid, Err: err},
+				"invalid value for %v %s: %v", f.FlagKnownAs, flagWithMinus(name), err)
+		}
+		if (f.procFlag != "" || attached) && long {
+			found := f.procFlag
+			f.procFlag = ""
+			return false, f.failFlag(&ParseError{Name: name, Value: []string{found}, Reason: ReasonUnwanted},
+				"%v unwanted argument %q found after: %s", f.FlagKnownAs, found, flagWithMinus(name))
+		}
+	case 1:
+		// It must have a value, which might be the next argument.
+		var hasValue bool
+		var value string
+		if f.procFlag != "" || attached {
+			// value directly follows flag
+			value = f.procFlag
+			hasValue = true
+			f.procFlag = ""
+		}
+		if !hasValue && f.fill(1) {
+			// value is the next arg
+			value, f.procArgs = f.procArgs[0], f.procArgs[1:]
+			hasValue = true
+		}
+		if !hasValue {
+			return false, f.failFlag(&ParseError{Name: name, Reason: ReasonMissing},
+				"%v needs an parameter: %s", f.FlagKnownAs, flagWithMinus(name))
+		}
+		var token bool
+		if vals, token = f.clearValue(flag, []string{value}); token && len(vals) == 0 {
+			return false, nil
+		}
+		given := vals // as given, without any indirection resolved
+		if vals, err = f.prepareValues(flag, name, vals); err != nil {
+			return false, err
+		}
+		if err := flag.set(name, vals); err != nil {
+			err = f.hideResolved(err, given, vals)
+			pe := &ParseError{Name: name, Value: given, Reason: ReasonInvalid, Err: err}
+			if value == "" {
+				pe.Reason = ReasonEmpty
+				return false, f.failFlag(pe, "empty value for %v %s", f.FlagKnownAs, flagWithMinus(name))
+			}
+			return false, f.failFlag(pe, "invalid value %q for %v %s: %v",
+				value, f.FlagKnownAs, flagWithMinus(name), err)
+		}
+	case -1:
+		// Dynamic set of strings, returned as a slice
+		toSet := []string{}
+		if (f.procFlag != "" || attached) && long {
+			found := f.procFlag
+			f.procFlag = ""
+			if !f.isClearToken(flag, found) {
+				return false, f.failFlag(&ParseError{Name: name, Value: []string{found}, Reason: ReasonUnwanted},
+					"%v unwanted argument %q found after: %s", f.FlagKnownAs, found, flagWithMinus(name))
+			}
+			// the clear token may be attached, as in --tags=
+			toSet = append(toSet, found)
+		}
+		for f.fill(1) {
+			if len(f.procArgs[0]) > 0 && (f.procArgs[0][0] != '-' || IsStdinDash(f.procArgs[0])) {
+				toSet = append(toSet, f.procArgs[0])
+				f.procArgs = f.procArgs[1:]
+			} else {
+				break
+			}
+		}
+		var token bool
+		if vals, token = f.clearValue(flag, toSet); token && len(vals) == 0 {
+			return false, nil
+		}
+		given := vals // as given, without any indirection resolved
+		if vals, err = f.prepareValues(flag, name, vals); err != nil {
+			return false, err
+		}
+		if err := flag.set(name, vals); err != nil {
+			err = f.hideResolved(err, given, vals)
+			return false, f.failFlag(&ParseError{Name: name, Value: given, Reason: ReasonInvalid, Err: err},
+				"invalid values %q for %v %s: %v", given, f.FlagKnownAs, flagWithMinus(name), err)
+		}
+
+	default:
+		if f.procFlag != "" || attached {
+			return false, f.failFlag(&ParseError{Name: name, Value: []string{f.procFlag}, Reason: ReasonUnwanted},
+				"%v needs more than one parameter: %s", f.FlagKnownAs, flagWithMinus(name))
+		}
+		if f.fill(1) && f.isClearToken(flag, f.procArgs[0]) {
+			// the clear token stands in place of the values
+			flag.Value.(clearable).Clear()
+			f.procArgs = f.procArgs[1:]
+			return false, nil
+		}
+		if !f.fill(flag.ArgsNeeded) {
+			return false, f.failFlag(&ParseError{Name: name, Value: append([]string{}, f.procArgs...), Reason: ReasonMissing},
+				"%v not enough parameters provided: %s", f.FlagKnownAs, flagWithMinus(name))
+		}
+		vals = append([]string{}, f.procArgs[:flag.ArgsNeeded]...)
+		f.procArgs = f.procArgs[flag.ArgsNeeded:]
+		given := vals // as given, without any indirection resolved
+		if vals, err = f.prepareValues(flag, name, vals); err != nil {
+			return false, err
+		}
+		if err := flag.set(name, vals); err != nil {
+			err = f.hideResolved(err, given, vals)
+			return false, f.failFlag(&ParseError{Name: name, Value: given, Reason: ReasonInvalid, Err: err},
+				"invalid values %q for %v %s: %v", given, f.FlagKnownAs, flagWithMinus(name), err)
+		}
+	}
+	f.mulock.Lock()
+	if f.actual == nil {
+		f.actual = make([]*Flag, 0)
+	}
+	f.actual = append(f.actual, flag)
+	if f.occurrences == nil {
+		f.occurrences = make(map[*Flag]int)
+	}
+	f.occurrences[flag]++
+	if f.recordOrder {
+		f.ordered = append(f.ordered, Occurrence{Name: name, Value: vals})
+	}
+	f.mulock.Unlock()
+	if f.onFlag != nil {
+		f.onFlag(name, vals)
+	}
+	return
+}
+
+// Use adds mw to the chain of middleware called, in the order added, with
+// each flag seen by Parse before its Value is set.  Middleware may observe the
+// flag, such as for metrics or auditing, change its Tokens, or return an
+// error to fail parsing.
+func (f *FlagSet) Use(mw func(pf *ParsedFlag) error) {
+	f.middleware = append(f.middleware, mw)
 }
 
-// Uint64 defines a uint64 flag with specified name, default value, and usage string.
-// The return value is the address of a uint64 variable that stores the value of the flag.
-func Uint64(name string, value uint64, usage string, typeExp string) *uint64 {
-	return CommandLine.Uint64(name, value, usage, typeExp)
+// Use adds mw to the chain of middleware called with each command-line flag
+// seen, see FlagSet.Use.
+func Use(mw func(pf *ParsedFlag) error) {
+	CommandLine.Use(mw)
 }
 
-// StringVar defines a string flag with specified name, default value, and usage string.
-// The argument p points to a string variable in which to store the value of the flag.
-func (f *FlagSet) StringVar(p *string, name string, value string, usage string, typeExp string) {
-	f.Var(newStringValue(value, p), name, usage, typeExp, 1)
+// prepareValues resolves any indirection in the values, see
+// SetValueIndirection, then hands the flag and its values to each middleware
+// in turn and returns the values as they were left.
+func (f *FlagSet) prepareValues(flag *Flag, name string, vals []string) ([]string, error) {
+	given := vals // kept for errors, so resolved secrets are not shown
+	if f.valueIndirection {
+		resolved := make([]string, len(vals))
+		for i, v := range vals {
+			r, err := resolveIndirect(v)
+			if err != nil {
+				return nil, f.failFlag(&ParseError{Name: name, Value: []string{v}, Reason: ReasonInvalid, Err: err},
+					"invalid value %q for %v %s: %v", v, f.FlagKnownAs, flagWithMinus(name), err)
+			}
+			resolved[i] = r
+		}
+		vals = resolved
+	}
+	if len(f.middleware) == 0 {
+		return vals, nil
+	}
+	pf := &ParsedFlag{Flag: flag, Name: name, Tokens: vals, Index: f.occurrence(flag)}
+	for _, mw := range f.middleware {
+		if err := mw(pf); err != nil {
+			return nil, f.failFlag(&ParseError{Name: name, Value: given, Reason: ReasonInvalid, Err: err},
+				"%v %s: %v", f.FlagKnownAs, flagWithMinus(name), err)
+		}
+	}
+	return pf.Tokens, nil
 }
 
-// StringVar defines a string flag with specified name, default value, and usage string.
-// The argument p points to a string variable in which to store the value of the flag.
-func StringVar(p *string, name string, value string, usage string, typeExp string) {
-	CommandLine.Var(newStringValue(value, p), name, usage, typeExp, 1)
+// hideResolved returns err with any values resolved from indirection, see
+// SetValueIndirection, shown as given, so secrets are not shown in errors.
+func (f *FlagSet) hideResolved(err error, given, vals []string) error {
+	if !f.valueIndirection {
+		return err
+	}
+	msg := err.Error()
+	for i, v := range vals {
+		if i < len(given) && v != given[i] && v != "" {
+			q := strconv.Quote(v)
+			msg = strings.ReplaceAll(msg, q[1:len(q)-1], given[i])
+			msg = strings.ReplaceAll(msg, v, given[i])
+		}
+	}
+	if msg == err.Error() {
+		return err
+	}
+	return errors.New(msg)
 }
 
-// String defines a string flag with specified name, default value, and usage string.
-// The return value is the address of a string variable that stores the value of the flag.
-func (f *FlagSet) String(name string, value string, usage string, typeExp string) *string {
-	p := new(string)
-	f.StringVar(p, name, value, usage, typeExp)
-	return p
+// resolveIndirect returns the value of the environment variable for a value
+// of the form @env:NAME, the contents of the file for @file:PATH, without a
+// final newline, or else the value as given.
+func resolveIndirect(v string) (string, error) {
+	switch {
+	case strings.HasPrefix(v, "@env:"):
+		name := v[len("@env:"):]
+		val, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s not set", name)
+		}
+		return val, nil
+	case strings.HasPrefix(v, "@file:"):
+		b, err := os.ReadFile(v[len("@file:"):])
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSuffix(strings.TrimSuffix(string(b), "\n"), "\r"), nil
+	}
+	return v, nil
 }
 
-// String defines a string flag with specified name, default value, and usage string.
-// The return value is the address of a string variable that stores the value of the flag.
-func String(name string, value string, usage string, typeExp string) *string {
-	return CommandLine.String(name, value, usage, typeExp)
+// Parse parses flag definitions from the argument list, which should not
+// include the command name.  Must be called after all flags in the FlagSet
+// are defined and before flags are accessed by the program.
+// The return value will be ErrHelp if --help or -h was set but not defined.
+// If AllowIntersperse is set, arguments and flags can be interspersed, that
+// is flags can follow positional arguments.
+func (f *FlagSet) Parse(arguments []string) error {
+	f.helpRequested = false
+	f.dumpFlags = false
+	f.args = nil
+	f.rawArgs = nil
+	f.ordered = nil
+	f.occurrences = nil
+	return f.parseArgs(arguments)
 }
 
-// StringSliceVar defines a string flag with specified name, default value, and usage string.
-// The argument p points to a string variable in which to store the value of the flag.
-func (f *FlagSet) StringSliceVar(p *([]string), name string, usage string, typeExp string, perFlag int) {
-	if perFlag <= 0 {
-		perFlag = -1
+// ParseFunc parses flags like Parse, but pulls the arguments one at a time
+// from next, which returns false when there are no more.  Arguments are only
+// pulled as far as needed, so a very long list never has to be held at once,
+// unless it all follows the flags and is kept for Args.
+func (f *FlagSet) ParseFunc(next func() (string, bool)) error {
+	f.procNext = next
+	defer func() { f.procNext = nil }()
+	return f.Parse(nil)
+}
+
+// ParseHandlingHelp parses the argument list like Parse, but reports the
+// built-in help having been shown as helpRequested rather than as the error
+// ErrHelp, so the caller can exit cleanly without comparing errors.
+//
+// Example:
+//   if help, err := fs.ParseHandlingHelp(args); help {
+//     os.Exit(0)
+//   } else if err != nil {
+//     os.Exit(2)
+//   }
+func (f *FlagSet) ParseHandlingHelp(arguments []string) (helpRequested bool, err error) {
+	err = f.Parse(arguments)
+	if f.helpRequested && err == ErrHelp {
+		return true, nil
 	}
-	f.Var(newStringSliceValue([]string{}, p), name, usage, typeExp, perFlag)
+	return false, err
 }
 
-// StringSliceVar defines a string flag with specified name, default value, and usage string.
-// The argument p points to a string variable in which to store the value of the flag.
-func StringSliceVar(p *([]string), name string, usage string, typeExp string, perFlag int) {
-	if perFlag <= 0 {
-		perFlag = -1
+// TokenizeAndParse splits line into arguments as a POSIX shell would, and then
+// parses them like Parse.  Arguments are separated by unquoted white space,
+// single quotes keep everything up to the next single quote, double quotes
+// keep everything up to the next unescaped double quote, and a backslash
+// escapes the next character, so `--name "a b"`, `--name 'a b'` and
+// `--name a\ b` are the same.  No variables or globs are expanded.
+func (f *FlagSet) TokenizeAndParse(line string) error {
+	args, err := tokenize(line)
+	if err != nil {
+		return f.handleError(f.failf("%v", err))
 	}
-	CommandLine.Var(newStringSliceValue([]string{}, p), name, usage, typeExp, perFlag)
+	return f.Parse(args)
 }
 
-// StringSlice defines a string flag with specified name, default value, and usage string.
-// The return value is the address of a string variable that stores the value of the flag.
-func (f *FlagSet) StringSlice(name string, usage string, typeExp string, perFlag int) *[]string {
-	p := new([]string)
-	f.StringSliceVar(p, name, usage, typeExp, perFlag)
-	return p
+// tokenize splits line into words following the quoting rules of a shell.
+func tokenize(line string) ([]string, error) {
+	var args []string
+	var word bytes.Buffer
+	inWord := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inWord {
+				args = append(args, word.String())
+				word.Reset()
+				inWord = false
+			}
+		case c == '\\':
+			if i+1 == len(line) {
+				return nil, errors.New("trailing backslash")
+			}
+			i++
+			if line[i] != '\n' {
+				inWord = true
+				word.WriteByte(line[i])
+			}
+		case c == '\'':
+			inWord = true
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			word.WriteString(line[i+1 : i+1+end])
+			i += end + 1
+		case c == '"':
+			inWord = true
+			for i++; ; i++ {
+				if i == len(line) {
+					return nil, errors.New("unterminated double quote")
+				}
+				if line[i] == '"' {
+					break
+				}
+				if line[i] == '\\' && i+1 < len(line) && strings.IndexByte("\"\\$`\n", line[i+1]) >= 0 {
+					i++
+					if line[i] == '\n' {
+						continue
+					}
+				}
+				word.WriteByte(line[i])
+			}
+		default:
+			inWord = true
+			word.WriteByte(c)
+		}
+	}
+	if inWord {
+		args = append(args, word.String())
+	}
+	return args, nil
 }
 
-// StringSlice defines a string flag with specified name, default value, and usage string.
-// The return value is the address of a string variable that stores the value of the flag.
-func StringSlice(name string, usage string, typeExp string, perFlag int) *[]string {
-	return CommandLine.StringSlice(name, usage, typeExp, perFlag)
+// ParseMore parses another stage of arguments, like Parse, but adds to the
+// state left by previous calls to Parse or ParseMore rather than clearing it.
+// The remaining arguments are appended to Args and the flags set are added
+// to those already seen, which allows for parsing global flags and then the
+// flags of a subcommand in the same set.  Parsed reports true after either.
+func (f *FlagSet) ParseMore(arguments []string) error {
+	return f.parseArgs(arguments)
 }
 
-// Float64Var defines a float64 flag with specified name, default value, and usage string.
-// The argument p points to a float64 variable in which to store the value of the flag.
-func (f *FlagSet) Float64Var(p *float64, name string, value float64, usage string, typeExp string) {
-	f.Var(newFloat64Value(value, p), name, usage, typeExp, 1)
+// ParseUntilOperand parses flags from the argument list up to the first
+// non-flag argument, or just after the terminator "--", and returns how many
+// arguments were consumed.  The arguments which are left, also returned by
+// Args, can then be handed to a subcommand.
+//
+// Example:
+//   prog -v --name x sub --flag   // consumed is 3, Args() is [sub --flag]
+func (f *FlagSet) ParseUntilOperand(arguments []string) (consumed int, err error) {
+	f.stopAtOperand = true
+	defer func() { f.stopAtOperand = false }()
+	err = f.Parse(arguments)
+	consumed = len(arguments) - len(f.procArgs)
+	f.args = append(f.args, f.procArgs...)
+	f.procArgs = nil
+	if err == nil {
+		if err = f.filterArgs(); err != nil {
+			err = f.handleError(err)
+		}
+	}
+	return
 }
 
-// Float64Var defines a float64 flag with specified name, default value, and usage string.
-// The argument p points to a float64 variable in which to store the value of the flag.
-func Float64Var(p *float64, name string, value float64, usage string, typeExp string) {
-	CommandLine.Var(newFloat64Value(value, p), name, usage, typeExp, 1)
+// Prescan looks through args for the named flags, without parsing them or
+// changing any state, and returns the values given for each name found, all
+// the values if given more than once.  This lets a program look at a mode
+// flag, such as --format, to decide which other flags to define before the
+// real Parse.  Scanning stops at the terminator "--".
+//
+// The named flags should be defined so the number of values each takes is
+// known, a name not defined only gets a value attached with "=".
+//
+// Example:
+//   fs.Prescan([]string{"-v", "--format=json", "x"}, "format")
+//   // map[format:[json]]
+func (f *FlagSet) Prescan(args []string, names ...string) map[string][]string {
+	found := make(map[string][]string)
+	// want returns the requested name the given name matches, if any
+	want := func(name string) (string, bool) {
+		flag := f.lookupArg(name)
+		for _, n := range names {
+			if n == name || (flag != nil && f.Lookup(n) == flag) {
+				return n, true
+			}
+		}
+		return "", false
+	}
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if a == "--" {
+			break
+		}
+		if len(a) < 2 || a[0] != '-' {
+			continue
+		}
+		name, value, long, attached := f.splitFlagArg(a)
+		if exp, ok := f.expansions[name]; ok && value == "" {
+			// scan the arguments the expansion is replaced by instead
+			args = append(append(append([]string{}, args[:i]...), exp...), args[i+1:]...)
+			i--
+			continue
+		}
+		flagNames := []string{name} // names in the argument, the last may take a value
+		if !long && !attached {
+			// the rest of a cluster is more single-rune flags, up to the first
+			// taking a value
+			for value != "" {
+				if flag := f.lookupArg(flagNames[len(flagNames)-1]); flag != nil && flag.ArgsNeeded != 0 {
+					attached = true
+					break
+				}
+				_, n := utf8.DecodeRuneInString(value)
+				flagNames = append(flagNames, value[:n])
+				value = value[n:]
+			}
+		}
+		for j, name := range flagNames {
+			flag := f.lookupArg(name)
+			var vals []string
+			if j == len(flagNames)-1 {
+				switch {
+				case attached:
+					vals = []string{value}
+				case flag == nil || flag.ArgsNeeded == 0:
+				case flag.ArgsNeeded == -1:
+					for i+1 < len(args) && args[i+1] != "" && (args[i+1][0] != '-' || IsStdinDash(args[i+1])) {
+						i++
+						vals = append(vals, args[i])
+					}
+				default:
+					for k := 0; k < flag.ArgsNeeded && i+1 < len(args); k++ {
+						i++
+						vals = append(vals, args[i])
+					}
+				}
+			}
+			if n, ok := want(name); ok {
+				found[n] = append(found[n], vals...)
+				if found[n] == nil {
+					found[n] = []string{}
+				}
+			}
+		}
+	}
+	return found
 }
 
-// Float64 defines a float64 flag with specified name, default value, and usage string.
-// The return value is the address of a float64 variable that stores the value of the flag.
-func (f *FlagSet) Float64(name string, value float64, usage string, typeExp string) *float64 {
-	p := new(float64)
-	f.Float64Var(p, name, value, usage, typeExp)
-	return p
+// Prescan looks through args for the named command-line flags, without
+// parsing them, see FlagSet.Prescan.
+func Prescan(args []string, names ...string) map[string][]string {
+	return CommandLine.Prescan(args, names...)
 }
 
-// Float64 defines a float64 flag with specified name, default value, and usage string.
-// The return value is the address of a float64 variable that stores the value of the flag.
-func Float64(name string, value float64, usage string, typeExp string) *float64 {
-	return CommandLine.Float64(name, value, usage, typeExp)
+// AddLazy adds fn to be called with the flag set when Parse next starts,
+// before any arguments are processed, to define flags late.  This lets
+// plugins define flags depending on the flags defined, or values prescanned,
+// before them.  Each fn is called only once.
+func (f *FlagSet) AddLazy(fn func(*FlagSet)) {
+	f.lazy = append(f.lazy, fn)
 }
 
-// DurationVar defines a time.Duration flag with specified name, default value, and usage string.
-// The argument p points to a time.Duration variable in which to store the value of the flag.
-func (f *FlagSet) DurationVar(p *time.Duration, name string, value time.Duration, usage string, typeExp string) {
-	f.Var(newDurationValue(value, p), name, usage, typeExp, 1)
+// AddLazy adds fn to be called with the command-line flag set when Parse
+// starts, to define flags late.
+func AddLazy(fn func(*FlagSet)) {
+	CommandLine.AddLazy(fn)
 }
 
-// DurationVar defines a time.Duration flag with specified name, default value, and usage string.
-// The argument p points to a time.Duration variable in which to store the value of the flag.
-func DurationVar(p *time.Duration, name string, value time.Duration, usage string, typeExp string) {
-	CommandLine.Var(newDurationValue(value, p), name, usage, typeExp, 1)
+// parseArgs parses the argument list into the flag set without clearing the
+// state from any previous calls.
+func (f *FlagSet) parseArgs(arguments []string) error {
+	if len(f.lazy) > 0 {
+		frozen := f.frozen
+		f.frozen = false
+		for len(f.lazy) > 0 {
+			fn := f.lazy[0]
+			f.lazy = f.lazy[1:]
+			fn(f)
+		}
+		f.frozen = frozen
+	}
+	f.parsed = true
+	f.rawArgs = append(f.rawArgs, arguments...)
+	f.procArgs = arguments
+	f.procFlag = ""
+	f.procAttached = false
+	for {
+		name, long, finished, err := f.parseOne()
+		if !finished {
+			if name != "" {
+				finished, err = f.parseFlagArg(name, long)
+			}
+		}
+		if err != nil {
+			return f.handleError(err)
+		}
+		if !finished {
+			continue
+		}
+		if err == nil {
+			break
+		}
+	}
+	if err := f.resolveEnv(); err != nil {
+		return f.handleError(err)
+	}
+	if err := f.resolveImplies(); err != nil {
+		return f.handleError(err)
+	}
+	if err := f.resolveDefaultsFrom(); err != nil {
+		return f.handleError(err)
+	}
+	if f.dumpFlags {
+		f.printValues(f.Output())
+		return f.handleError(ErrDumpFlags)
+	}
+	if err := f.checkExperimental(); err != nil {
+		return f.handleError(err)
+	}
+	if err := f.checkRequired(); err != nil {
+		return f.handleError(err)
+	}
+	if err := f.checkExactlyOne(); err != nil {
+		return f.handleError(err)
+	}
+	if err := f.checkRequires(); err != nil {
+		return f.handleError(err)
+	}
+	if !f.stopAtOperand {
+		if err := f.filterArgs(); err != nil {
+			return f.handleError(err)
+		}
+	}
+	if err := f.checkArgBounds(); err != nil {
+		return f.handleError(err)
+	}
+	return nil
 }
 
-// Duration defines a time.Duration flag with specified name, default value, and usage string.
-// The return value is the address of a time.Duration variable that stores the value of the flag.
-func (f *FlagSet) Duration(name string, value time.Duration, usage string, typeExp string) *time.Duration {
-	p := new(time.Duration)
-	f.DurationVar(p, name, value, usage, typeExp)
-	return p
+// SetSingleDashLong tells Parse to accept long names given with a single dash,
+// as the standard flag package does, so -verbose is the same as --verbose.
+// As -verbose could also be a cluster of single-rune flags, such as -v -e -r,
+// a long name which is defined always wins, and the argument is treated as a
+// cluster only when no such long name is defined.
+func (f *FlagSet) SetSingleDashLong(singleDashLong bool) {
+	f.singleDashLong = singleDashLong
 }
 
-// Duration defines a time.Duration flag with specified name, default value, and usage string.
-// The return value is the address of a time.Duration variable that stores the value of the flag.
-func Duration(name string, value time.Duration, usage string, typeExp string) *time.Duration {
-	return CommandLine.Duration(name, value, usage, typeExp)
+// SetSingleDashLong tells Parse to accept long command-line flag names given
+// with a single dash, see FlagSet.SetSingleDashLong.
+func SetSingleDashLong(singleDashLong bool) {
+	CommandLine.singleDashLong = singleDashLong
 }
 
-// FlagFunc defines a flag with the specified name and usage string.
-// Each time the flag is seen, fn is called with the value of the flag.
-// If fn returns a non-nil error, it will be treated as a flag value parsing error.
-func (f *FlagSet) FlagFunc(name, usage string, typeExp string, argsNeeded int, fn func([]string) error) {
-	f.Var(flagFuncValue(fn), name, usage, typeExp, argsNeeded)
+// RecordOrder tells Parse to keep each flag seen, with its values, in the
+// order given on the command line, for use where the order matters across
+// different flags, such as "-i in1 -c copy -i in2".
+func (f *FlagSet) RecordOrder(recordOrder bool) {
+	f.recordOrder = recordOrder
 }
 
-// FlagFunc defines a flag with the specified name and usage string.
-// Each time the flag is seen, fn is called with the value of the flag.
-// If fn returns a non-nil error, it will be treated as a flag value parsing error.
-func FlagFunc(name, usage string, typeExp string, argsNeeded int, fn func([]string) error) {
-	CommandLine.FlagFunc(name, usage, typeExp, argsNeeded, fn)
+// OrderedActuals returns the flags seen by the last Parse, and any following
+// ParseMore, in the order given on the command line.  Flags are only kept
+// when RecordOrder is set.  The result is a copy, which may be changed freely.
+func (f *FlagSet) OrderedActuals() []Occurrence {
+	f.mulock.Lock()
+	defer f.mulock.Unlock()
+	ordered := make([]Occurrence, len(f.ordered))
+	for i, o := range f.ordered {
+		ordered[i] = Occurrence{Name: o.Name, Value: append([]string{}, o.Value...)}
+	}
+	return ordered
 }
 
-// Var defines a flag with the specified name and usage string. The type and
-// value of the flag are represented by the first argument, of type Value, which
-// typically holds a user-defined implementation of Value. For instance, the
-// caller could create a flag that turns a comma-separated string into a slice
-// of strings by giving the slice the methods of Value; in particular, Set would
-// decompose the comma-separated string into the slice.
-func (f *FlagSet) Var(value Value, flagStr string, usage string, typeExp string, args int) {
-	names := splitOn(flagStr, ' ', -1)
+// SetArgsFilter sets fn to be applied to the non-flag arguments at the end of
+// Parse, such as for expanding globs where the shell does not, and Args then
+// returns what fn returns.  An error from fn is handled like a parse error.
+// For ParseUntilOperand, fn is given all the arguments left after the flags.
+func (f *FlagSet) SetArgsFilter(fn func([]string) ([]string, error)) {
+	f.argsFilter = fn
+}
 
-	// Make sure the single char is second, if there is one
-	if len(names) > 1 { // TODO: fix for more than two
-		if rlen(names[0]) == 1 && rlen(names[1]) > 1 {
-			names[0], names[1] = names[1], names[0]
-		}
-	}
+// SetArgsFilter sets fn to be applied to the non-flag command-line arguments
+// at the end of Parse.
+func SetArgsFilter(fn func([]string) ([]string, error)) {
+	CommandLine.argsFilter = fn
+}
 
-	// Remember the default value as a string; it won't change.
-	flag := &Flag{
-		Name:         names,
-		Usage:        usage,
-		Value:        value,
-		DefValue:     value.String(),
-		TypeExpected: typeExp,
-		ArgsNeeded:   args,
-		Grouping:     f.curGrouping,
+// filterArgs replaces the non-flag arguments with those returned by the
+// filter set with SetArgsFilter, if any.
+func (f *FlagSet) filterArgs() error {
+	if f.argsFilter == nil {
+		return nil
 	}
-
-	// Check if the flag exists already
-	for _, name := range names {
-		alreadythere := f.Lookup(name)
-		if alreadythere != nil {
-			fmt.Fprintf(f.Output(), "%s %v redefined: %s\n", f.name, f.FlagKnownAs, name)
-			panic(fmt.Sprintf("%v redefinition", f.FlagKnownAs)) // Happens only if flags are declared with identical names
-		}
+	args, err := f.argsFilter(f.args)
+	if err != nil {
+		return f.failf("%v", err)
 	}
+	f.args = args
+	return nil
+}
 
-	// Go ahead and add this new flag
-	if f.formal == nil {
-		f.formal = make([]*Flag, 0)
-	}
-	f.formal = append(f.formal, flag)
+// SetNoPositional tells Parse to fail on any non-flag argument, for programs
+// which take only flags.  This catches a misspelled flag value falling
+// through to the arguments.
+func (f *FlagSet) SetNoPositional(noPositional bool) {
+	f.noPositional = noPositional
 }
 
-// Var defines a flag with the specified name and usage string. The type and
-// value of the flag are represented by the first argument, of type Value, which
-// typically holds a user-defined implementation of Value. For instance, the
-// caller could create a flag that turns a comma-separated string into a slice
-// of strings by giving the slice the methods of Value; in particular, Set would
-// decompose the comma-separated string into the slice.
-func Var(value Value, name string, usage string, typeExp string, argsNeeded int) {
-	CommandLine.Var(value, name, usage, typeExp, argsNeeded)
+// SetNoPositional tells Parse to fail on any non-flag command-line argument.
+func SetNoPositional(noPositional bool) {
+	CommandLine.noPositional = noPositional
 }
 
-// failf prints to standard error a formatted error and usage message and
-// returns the error.
-func (f *FlagSet) failf(format string, a ...interface{}) error {
-	err := fmt.Errorf(format, a...)
-	fmt.Fprintln(f.Output(), err)
-	f.usage()
-	return err
+// SetMaxArgs makes Parse fail as soon as more than n non-flag arguments are
+// seen, rather than after all are read as with SetArgBounds.  Along with
+// ParseFunc, this guards against runaway memory use from a malformed source
+// of arguments.  A negative n removes the limit, which is the default.
+func (f *FlagSet) SetMaxArgs(n int) {
+	f.argLimit = n >= 0
+	f.maxArgs = n
 }
 
-// usage calls the Usage method for the flag set, or the usage function if
-// the flag set is CommandLine.
-func (f *FlagSet) usage() {
-	if f.Usage == nil {
-		if f == CommandLine {
-			Usage()
-		} else {
-			defaultUsage(f)
-		}
-	} else {
-		f.Usage()
-	}
+// SetMaxArgs makes Parse fail as soon as more than n non-flag command-line
+// arguments are seen, see FlagSet.SetMaxArgs.
+func SetMaxArgs(n int) {
+	CommandLine.SetMaxArgs(n)
 }
 
-func (f *FlagSet) parseOne() (flagName string, long, finished bool, err error) {
-	if len(f.procArgs) == 0 {
-		finished = true
-		return
+// checkMaxArgs ensures there are no more arguments than SetMaxArgs allows.
+func (f *FlagSet) checkMaxArgs() error {
+	if f.argLimit && len(f.args) > f.maxArgs {
+		return f.failf("too many arguments, at most %d allowed", f.maxArgs)
 	}
+	return nil
+}
 
-	// processing previously encountered single-rune flag
-	if flag := f.procFlag; len(flag) > 0 {
-		_, n := utf8.DecodeRuneInString(flag)
-		f.procFlag = flag[n:]
-		flagName = flag[0:n]
-		return
-	}
+// SetArgBounds sets the number of non-flag arguments which must be left after
+// parsing, between min and max inclusive, where a max of -1 means no limit.
+// Parse fails if the count of Args is out of bounds.
+func (f *FlagSet) SetArgBounds(min, max int) {
+	f.argBounds = true
+	f.argMin = min
+	f.argMax = max
+}
 
-	a := f.procArgs[0]
+// SetArgBounds sets the number of non-flag command-line arguments which must
+// be given, see FlagSet.SetArgBounds.
+func SetArgBounds(min, max int) {
+	CommandLine.SetArgBounds(min, max)
+}
 
-	// one non-flag argument
-	if a == "-" || a == "" || a[0] != '-' {
-		if f.allowIntersperse {
-			f.args = append(f.args, a)
-			f.procArgs = f.procArgs[1:]
-			return
-		}
-		f.args = append(f.args, f.procArgs...)
-		f.procArgs = nil
-		finished = true
-		return
+// NArgExpected returns the minimum and maximum number of non-flag arguments
+// expected, where a max of -1 means no limit.  These are the bounds given to
+// SetArgBounds or, if not set, one for each of Params.
+func (f *FlagSet) NArgExpected() (min, max int) {
+	if f.argBounds {
+		return f.argMin, f.argMax
 	}
+	if len(f.Params) > 0 {
+		return len(f.Params), len(f.Params)
+	}
+	return 0, -1
+}
 
-	// end of flags
-	if f.procArgs[0] == "--" {
-		f.args = append(f.args, f.procArgs[1:]...)
-		f.procArgs = nil
-		finished = true
-		return
+// NArgExpected returns the minimum and maximum number of non-flag
+// command-line arguments expected, see FlagSet.NArgExpected.
+func NArgExpected() (min, max int) {
+	return CommandLine.NArgExpected()
+}
+
+// checkArgBounds ensures there are no arguments if SetNoPositional is set, and
+// that the number of arguments is within the bounds given to SetArgBounds.
+func (f *FlagSet) checkArgBounds() error {
+	if f.stopAtOperand {
+		return nil
 	}
+	if f.noPositional && len(f.args) > 0 {
+		return f.failf("unexpected argument: %s", f.args[0])
+	}
+	if !f.argBounds {
+		return nil
+	}
+	n := len(f.args)
+	switch {
+	case f.argMin == f.argMax && n != f.argMin:
+		return f.failf("expected %d arguments, got %d", f.argMin, n)
+	case n < f.argMin:
+		return f.failf("expected at least %d arguments, got %d", f.argMin, n)
+	case f.argMax >= 0 && n > f.argMax:
+		return f.failf("expected at most %d arguments, got %d", f.argMax, n)
+	}
+	return nil
+}
 
-	// long flag signified with "--" prefix
-	if a[1] == '-' {
-		long = true
-		if parts := splitOn(a, '=', 2); len(parts) > 1 {
-			flagName = parts[0][2:]
-			f.procFlag = parts[1]
-			f.procArgs = f.procArgs[1:]
-			if flagName == "" {
-				err = fmt.Errorf("empty %v in argument %q", f.FlagKnownAs, a)
-			}
-			return
+// checkRequired ensures all the flags marked as required have been set.
+func (f *FlagSet) checkRequired() error {
+	var missing []string
+	for _, flag := range f.formal {
+		if flag.Required && !f.isSet(flag) {
+			missing = append(missing, displayNames(flag)[0])
 		}
-		flagName = a[2:]
-		f.procArgs = f.procArgs[1:]
-		return
 	}
+	switch len(missing) {
+	case 0:
+		return nil
+	case 1:
+		return f.failFlag(&ParseError{Name: missing[0], Reason: ReasonRequired},
+			"required %v not provided: %s", f.FlagKnownAs, flagWithMinus(missing[0]))
+	}
+	names := make([]string, len(missing))
+	for i, name := range missing {
+		names[i] = flagWithMinus(name)
+	}
+	return f.failFlag(&ParseError{Name: missing[0], Names: missing, Reason: ReasonRequired},
+		"required %vs not provided: %s", f.FlagKnownAs, strings.Join(names, ", "))
+}
 
-	// some number of single-rune flags
-	a = a[1:]
-	_, n := utf8.DecodeRuneInString(a)
-	if len(a) > n && a[n] == '=' {
-		flagName = a[0:n]
-		f.procFlag = a[n+1:]
-		f.procArgs = f.procArgs[1:]
-		return
+// MarkRequired makes Parse fail when any of the named flags is not set,
+// listing all those missing.  Required flags are marked as such in the help.
+func (f *FlagSet) MarkRequired(names ...string) {
+	for _, name := range names {
+		f.mustLookup(name).Required = true
 	}
-	flagName = a[0:n]
-	f.procFlag = a[n:]
-	f.procArgs = f.procArgs[1:]
-	return
 }
 
-func contains(a []string, b []string) bool {
-	found := false
-contains_outer:
-	for _, B := range a {
-		found = false
-		for _, A := range a {
-			if A == B {
-				found = true
-				continue contains_outer
-			}
+// MarkRequired makes Parse fail when any of the named command-line flags is
+// not set.
+func MarkRequired(names ...string) {
+	CommandLine.MarkRequired(names...)
+}
+
+// printValues writes the names and current values of all the flags to w.
+func (f *FlagSet) printValues(w io.Writer) {
+	f.VisitAll(func(flag *Flag) {
+		if flag.Value == (*presentValue)(&f.dumpFlags) {
+			return
 		}
-		if !found {
-			return false
+		var names []string
+		for _, n := range displayNames(flag) {
+			names = append(names, flagWithMinus(n))
+		}
+		fmt.Fprintf(w, "%s: %s\n", strings.Join(names, f.nameSep()), flag.Value.String())
+	})
+}
+
+// handleError applies the error handling policy of the flag set to an error
+// encountered while parsing.
+func (f *FlagSet) handleError(err error) error {
+	switch f.errorHandling {
+	case ContinueOnError:
+		return err
+	case ExitOnError:
+		if err == ErrHelp || err == ErrDumpFlags {
+			os.Exit(0)
 		}
+		os.Exit(2)
+	case PanicOnError:
+		panic(err)
 	}
+	return err
+}
 
-	return found
+// isSet reports whether the flag has been set, either by Parse or by Set.
+func (f *FlagSet) isSet(flag *Flag) bool {
+	return f.count(flag) > 0
 }
 
-func flagWithMinus(name string) string {
-	if rlen(name) > 1 {
-		return "--" + name
+// count returns the number of times the flag has been set.
+func (f *FlagSet) count(flag *Flag) int {
+	f.mulock.Lock()
+	defer f.mulock.Unlock()
+	var n int
+	for _, a := range f.actual {
+		if a == flag {
+			n++
+		}
 	}
-	return "-" + name
+	return n
 }
 
-func (f *FlagSet) parseFlagArg(name string, long bool) (finished bool, err error) {
+// Occurrences returns the number of times the named flag was given, by any of
+// its names, on the command line to the last Parse and any following
+// ParseMore.  Flags set from the environment or by Set are not counted.  This
+// is helpful for warning about a flag given more than once, or for counting
+// -v -v -v for verbosity.
+func (f *FlagSet) Occurrences(name string) int {
 	flag := f.Lookup(name)
 	if flag == nil {
-		if name == "help" || name == "h" { // special case for nice help message.
-			f.usage()
-			ErrHelp = errors.New(fmt.Sprintf("%v: %v", f.FlagKnownAs, ErrHelp.Error()))
-			return false, ErrHelp
-		}
-		if name == "get-bash-completion" {
-			if contains(os.Environ(),
-				[]string{"COMP_TYPE", "COMP_LINE", "COMP_POINT", "COMP_KEY"}) {
-				if len(os.Args) >= 3 {
-					if len(os.Args[3]) > 0 && strings.HasPrefix("-", os.Args[3]) {
-						fmt.Println("--")
-					}
-					for _, flag := range f.formal {
-						for _, name := range flag.Name {
-							if len(os.Args[3]) > 0 && strings.HasPrefix(flagWithMinus(name), os.Args[3]) {
-								fmt.Println(flagWithMinus(name))
-							}
-						}
-					}
-				}
-			}
-			os.Exit(0)
-			//fmt.Fprintf(os.Stderr, "go\nmake\nmy day\n")
-			//fmt.Printf("makeme\n")
-			fmt.Printf("&&go -- test\nmake -- my\nmy day -- plac3\n")
-			fmt.Fprintf(os.Stderr, "%#v\n", os.Args)
-			for _, e := range os.Environ() {
-				if strings.HasPrefix(e, "COM") {
-					pair := strings.SplitN(e, "=", 2)
-					fmt.Fprintf(os.Stderr, "  %v=%q\n", pair[0], pair[1])
-				}
-			}
-		}
-		// Print --xxx when flag is more than one rune.
-		return false, f.failf("%v provided but not defined: %s",
-			f.FlagKnownAs, flagWithMinus(name))
+		return 0
 	}
-	switch flag.ArgsNeeded {
-	case 0:
-		// Param doesn't need an arg.
-		flag.Value.Set([]string{})
-		if f.procFlag != "" && long {
-			found := f.procFlag
-			f.procFlag = ""
-			return false, f.failf("%v unwanted argument %q found after: %s",
-				f.FlagKnownAs, found, flagWithMinus(name))
-		}
-	case 1:
-		// It must have a value, which might be the next argument.
-		var hasValue bool
-		var value string
-		if f.procFlag != "" {
-			// value directly follows flag
-			value = f.procFlag
-			hasValue = true
-			f.procFlag = ""
-		}
-		if !hasValue && len(f.procArgs) > 0 {
-			// value is the next arg
-			value, f.procArgs = f.procArgs[0], f.procArgs[1:]
-			hasValue = true
-		}
-		if !hasValue {
-			return false, f.failf("%v needs an parameter: %s",
-				f.FlagKnownAs, flagWithMinus(name))
-		}
-		if err := flag.Value.Set([]string{value}); err != nil {
-			return false, f.failf("invalid value %q for %v %s: %v",
-				value, f.FlagKnownAs, flagWithMinus(name), err)
+	return f.occurrence(flag)
+}
+
+// occurrence returns the number of times the flag was given since Parse.
+func (f *FlagSet) occurrence(flag *Flag) int {
+	f.mulock.Lock()
+	defer f.mulock.Unlock()
+	return f.occurrences[flag]
+}
+
+// Occurrences returns the number of times the named command-line flag has
+// been set.
+func Occurrences(name string) int {
+	return CommandLine.Occurrences(name)
+}
+
+// envName returns the environment variable the flag may be set from, either
+// given by SetEnv or derived from the prefix set by SetEnvPrefix.
+func (f *FlagSet) envName(flag *Flag) string {
+	if flag.Env != "" || f.envPrefix == "" {
+		return flag.Env
+	}
+	prefix := f.envPrefix
+	if !strings.HasSuffix(prefix, "_") {
+		prefix += "_"
+	}
+	return strings.ToUpper(prefix + strings.ReplaceAll(flag.Name[0], "-", "_"))
+}
+
+// resolveEnv sets the flags which were not seen while parsing from their
+// environment variables.
+func (f *FlagSet) resolveEnv() error {
+	for _, flag := range f.formal {
+		env := f.envName(flag)
+		if env == "" || f.isSet(flag) {
+			continue
 		}
-	case -1:
-		// Dynamic set of strings, returned as a slice
-		if f.procFlag != "" && long {
-			found := f.procFlag
-			f.procFlag = ""
-			return false, f.failf("%v unwanted argument %q found after: %s",
-				f.FlagKnownAs, found, flagWithMinus(name))
+		val, ok := os.LookupEnv(env)
+		if !ok {
+			continue
 		}
-
-		toSet := []string{}
-		for len(f.procArgs) > 0 {
-			if len(f.procArgs[0]) > 0 && f.procArgs[0][0] != '-' {
-				toSet = append(toSet, f.procArgs[0])
-				f.procArgs = f.procArgs[1:]
-			} else {
-				break
+		var vals []string
+		switch flag.ArgsNeeded {
+		case 0:
+			if on, err := strconv.ParseBool(val); err != nil || !on {
+				continue
 			}
+			vals = []string{}
+		case 1:
+			vals = []string{val}
+		default:
+			vals = strings.Fields(val)
+		}
+		if err := flag.set(flag.Name[0], vals); err != nil {
+			return f.failFlag(&ParseError{Name: flag.Name[0], Value: vals, Reason: ReasonInvalid, Err: err},
+				"invalid value %q from $%s for %v %s: %v", val, env, f.FlagKnownAs, flagWithMinus(flag.Name[0]), err)
 		}
-		flag.Value.Set(toSet)
+		f.mulock.Lock()
+		f.actual = append(f.actual, flag)
+		f.mulock.Unlock()
+	}
+	return nil
+}
 
-	default:
-		if f.procFlag != "" {
-			return false, f.failf("%v needs more than one parameter: %s",
-				f.FlagKnownAs, flagWithMinus(name))
+// resolveImplies sets the flags implied by the flags which were seen while
+// parsing, leaving alone any which were set explicitly.
+func (f *FlagSet) resolveImplies() error {
+	for _, imp := range f.implies {
+		if !f.isSet(f.Lookup(imp.name)) {
+			continue
 		}
-		if len(f.procArgs) < flag.ArgsNeeded {
-			return false, f.failf("%v not enough parameters provided: %s",
-				f.FlagKnownAs, flagWithMinus(name))
+		var targets []string
+		for target := range imp.sets {
+			targets = append(targets, target)
 		}
-		if err := flag.Value.Set(f.procArgs[:flag.ArgsNeeded]); err != nil {
-			return false, f.failf("invalid values %q for %v %s: %v",
-				f.procArgs[:flag.ArgsNeeded], f.FlagKnownAs, flagWithMinus(name), err)
+		sort.Strings(targets)
+		for _, target := range targets {
+			flag := f.Lookup(target)
+			if f.isSet(flag) {
+				continue
+			}
+			if err := flag.set(target, imp.sets[target]); err != nil {
+				return f.failFlag(&ParseError{Name: target, Value: imp.sets[target], Reason: ReasonInvalid, Err: err},
+					"invalid value %q implied by %s for %v %s: %v",
+					imp.sets[target], flagWithMinus(imp.name), f.FlagKnownAs, flagWithMinus(target), err)
+			}
 		}
 	}
-	f.mulock.Lock()
-	defer f.mulock.Unlock()
-	if f.actual == nil {
-		f.actual = make([]*Flag, 0)
-	}
-	f.actual = append(f.actual, flag)
-	return
+	return nil
 }
 
-// Parse parses flag definitions from the argument list, which should not
-// include the command name.  Must be called after all flags in the FlagSet
-// are defined and before flags are accessed by the program.
-// The return value will be ErrHelp if --help or -h was set but not defined.
-// If AllowIntersperse is set, arguments and flags can be interspersed, that
-// is flags can follow positional arguments.
-func (f *FlagSet) Parse(arguments []string) error {
-	f.parsed = true
-	f.procArgs = arguments
-	f.procFlag = ""
-	f.args = nil
-	for {
-		name, long, finished, err := f.parseOne()
-		if !finished {
-			if name != "" {
-				finished, err = f.parseFlagArg(name, long)
-			}
+// resolveDefaultsFrom copies the final value of each source flag into the
+// flags declared with SetDefaultFromFlag which were not set while parsing.  A
+// source taking its own default from another flag is resolved before the
+// flags taking theirs from it.
+func (f *FlagSet) resolveDefaultsFrom() error {
+	done := make([]bool, len(f.defaultsFrom))
+	var resolve func(i int) error
+	resolve = func(i int) error {
+		if done[i] {
+			return nil
 		}
-		if err != nil {
-			switch f.errorHandling {
-			case ContinueOnError:
-				return err
-			case ExitOnError:
-				if err == ErrHelp {
-					os.Exit(0)
+		done[i] = true
+		d := f.defaultsFrom[i]
+		// a source taking its default from another flag is resolved first
+		for j, s := range f.defaultsFrom {
+			if f.sameFlag(s.name, d.source) {
+				if err := resolve(j); err != nil {
+					return err
 				}
-				os.Exit(2)
-			case PanicOnError:
-				panic(err)
 			}
 		}
-		if !finished {
-			continue
+		flag, src := f.Lookup(d.name), f.Lookup(d.source)
+		if flag == nil {
+			return f.failFlag(&ParseError{Name: d.name, Reason: ReasonUndefined},
+				"no such %v %s", f.FlagKnownAs, flagWithMinus(d.name))
 		}
-		if err == nil {
-			break
+		if src == nil {
+			return f.failFlag(&ParseError{Name: d.source, Reason: ReasonUndefined},
+				"no such %v %s", f.FlagKnownAs, flagWithMinus(d.source))
+		}
+		if f.isSet(flag) {
+			return nil
+		}
+		var vals []string
+		if g, ok := src.Value.(Getter); ok {
+			vals, _ = g.Get().([]string)
+		}
+		if vals == nil {
+			vals = []string{src.Value.String()}
+		}
+		if err := flag.set(d.name, vals); err != nil {
+			return f.failFlag(&ParseError{Name: d.name, Value: vals, Reason: ReasonInvalid, Err: err},
+				"invalid default %q from %s for %v %s: %v",
+				vals, flagWithMinus(d.source), f.FlagKnownAs, flagWithMinus(d.name), err)
+		}
+		return nil
+	}
+	for i := range f.defaultsFrom {
+		if err := resolve(i); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -1469,6 +5506,12 @@ func (f *FlagSet) Parsed() bool {
 	return f.parsed
 }
 
+// HelpRequested reports whether the last call to f.Parse invoked the built-in
+// help, that is --help or -h was seen but not defined.
+func (f *FlagSet) HelpRequested() bool {
+	return f.helpRequested
+}
+
 // Parse parses the command-line flags from os.Args[1:].  Must be called
 // after all flags are defined and before flags are accessed by the program.
 // If AllowIntersperse is set, arguments and flags can be interspersed, that
@@ -1483,6 +5526,12 @@ func Parsed() bool {
 	return CommandLine.Parsed()
 }
 
+// HelpRequested reports whether parsing the command-line flags invoked the
+// built-in help.
+func HelpRequested() bool {
+	return CommandLine.HelpRequested()
+}
+
 // CommandLine is the default set of command-line flags, parsed from os.Args.
 // The top-level functions such as BoolVar, Arg, and so on are wrappers for the
 // methods of CommandLine.
@@ -1500,6 +5549,16 @@ func commandLineUsage() {
 	Usage()
 }
 
+// ResetCommandLine replaces CommandLine with a new, empty set of command-line
+// flags, as it was before any flags were defined.  This is helpful for test
+// suites which define flags on CommandLine in more than one test.  To keep
+// parse errors from exiting the program, follow it with
+//   CommandLine.Init(name, ContinueOnError)
+func ResetCommandLine() {
+	CommandLine = NewFlagSet("", ExitOnError)
+	CommandLine.Usage = commandLineUsage
+}
+
 // NewFlagSet returns a new, empty parameter set with the specified name and
 // error handling property.
 func NewFlagSet(name string, errorHandling ErrorHandling) *FlagSet {
@@ -1534,3 +5593,145 @@ func (f *FlagSet) Init(name string, errorHandling ErrorHandling) {
 	f.name = name
 	f.errorHandling = errorHandling
 }
+
+// A FlagBuilder defines a flag one attribute at a time, as an easier to read
+// alternative to the typed constructors for flags with many attributes.  It is
+// created by FlagSet.Flag and the flag is defined by Register.
+//
+// Example:
+//   fs.Flag("timeout t").Duration(30 * time.Second).Usage("how long to wait").
+//     Type("DUR").Required().Register(&timeout)
+type FlagBuilder struct {
+	fs       *FlagSet
+	name     string
+	def      interface{}
+	pres     bool
+	args     int
+	usage    string
+	typeExp  string
+	env      string
+	group    string
+	required bool
+	hidden   bool
+}
+
+// Flag starts the definition of a flag with the specified name, which may be
+// several names separated by spaces as with Var.  For command-line flags use
+// CommandLine.Flag.
+func (f *FlagSet) Flag(name string) *FlagBuilder {
+	return &FlagBuilder{fs: f, name: name, args: 1, group: f.curGrouping}
+}
+
+// Pres makes the flag a present flag, registered with a *bool.
+func (b *FlagBuilder) Pres() *FlagBuilder { b.pres, b.args = true, 0; return b }
+
+// Bool sets the default value of a flag registered with a *bool.
+func (b *FlagBuilder) Bool(value bool) *FlagBuilder { b.def = value; return b }
+
+// Int sets the default value of a flag registered with an *int.
+func (b *FlagBuilder) Int(value int) *FlagBuilder { b.def = value; return b }
+
+// Int64 sets the default value of a flag registered with an *int64.
+func (b *FlagBuilder) Int64(value int64) *FlagBuilder { b.def = value; return b }
+
+// Uint sets the default value of a flag registered with a *uint.
+func (b *FlagBuilder) Uint(value uint) *FlagBuilder { b.def = value; return b }
+
+// Uint64 sets the default value of a flag registered with a *uint64.
+func (b *FlagBuilder) Uint64(value uint64) *FlagBuilder { b.def = value; return b }
+
+// String sets the default value of a flag registered with a *string.
+func (b *FlagBuilder) String(value string) *FlagBuilder { b.def = value; return b }
+
+// Float64 sets the default value of a flag registered with a *float64.
+func (b *FlagBuilder) Float64(value float64) *FlagBuilder { b.def = value; return b }
+
+// Duration sets the default value of a flag registered with a *time.Duration.
+func (b *FlagBuilder) Duration(value time.Duration) *FlagBuilder { b.def = value; return b }
+
+// Args sets the number of arguments wanted by a flag registered with a Value,
+// or per flag for a *[]string, see StringSliceVar.
+func (b *FlagBuilder) Args(args int) *FlagBuilder { b.args = args; return b }
+
+// Usage sets the help message of the flag.
+func (b *FlagBuilder) Usage(usage string) *FlagBuilder { b.usage = usage; return b }
+
+// Type sets the hint on what is expected, shown in the help.
+func (b *FlagBuilder) Type(typeExp string) *FlagBuilder { b.typeExp = typeExp; return b }
+
+// Env sets the environment variable to use when the flag is not set, see SetEnv.
+func (b *FlagBuilder) Env(env string) *FlagBuilder { b.env = env; return b }
+
+// Group sets the grouping of the flag in the help, see GroupingSet.
+func (b *FlagBuilder) Group(group string) *FlagBuilder { b.group = group; return b }
+
+// Required makes Parse fail when the flag is not set.
+func (b *FlagBuilder) Required() *FlagBuilder { b.required = true; return b }
+
+// Hidden leaves the flag out of the help.
+func (b *FlagBuilder) Hidden() *FlagBuilder { b.hidden = true; return b }
+
+// Register defines the flag, storing its value in p.  The type of p must match
+// the default value given, if any: a *bool, *int, *int64, *uint, *uint64,
+// *string, *float64, *time.Duration, *[]string or a Value.
+func (b *FlagBuilder) Register(p interface{}) *Flag {
+	var value Value
+	var ok bool
+	switch p := p.(type) {
+	case *bool:
+		var d bool
+		if d, ok = b.def.(bool); b.pres {
+			value = newPresentValue(p)
+		} else {
+			value = newBoolValue(d, p)
+		}
+	case *int:
+		var d int
+		d, ok = b.def.(int)
+		value = newIntValue(d, p)
+	case *int64:
+		var d int64
+		d, ok = b.def.(int64)
+		value = newInt64Value(d, p)
+	case *uint:
+		var d uint
+		d, ok = b.def.(uint)
+		value = newUintValue(d, p)
+	case *uint64:
+		var d uint64
+		d, ok = b.def.(uint64)
+		value = newUint64Value(d, p)
+	case *string:
+		var d string
+		d, ok = b.def.(string)
+		value = newStringValue(d, p)
+	case *float64:
+		var d float64
+		d, ok = b.def.(float64)
+		value = newFloat64Value(d, p)
+	case *time.Duration:
+		var d time.Duration
+		d, ok = b.def.(time.Duration)
+		value = newDurationValue(d, p)
+	case *[]string:
+		value = newStringSliceValue([]string{}, p)
+		if b.args <= 1 {
+			b.args = -1
+		}
+	case Value:
+		value = p
+	default:
+		panic(fmt.Sprintf("%v %s: unsupported type %T", b.fs.FlagKnownAs, b.name, p))
+	}
+	if b.def != nil && !ok {
+		panic(fmt.Sprintf("%v %s: default %T does not match %T", b.fs.FlagKnownAs, b.name, b.def, p))
+	}
+
+	b.fs.Var(value, b.name, b.usage, b.typeExp, b.args)
+	flag := b.fs.Lookup(splitOn(b.name, ' ', -1)[0])
+	flag.Grouping = b.group
+	flag.Env = b.env
+	flag.Required = b.required
+	flag.Hidden = b.hidden
+	return flag
+}
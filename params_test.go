@@ -5,13 +5,18 @@
 package params_test
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
+	"flag"
 	"fmt"
 
 	//"internal/testenv"
 	"io"
+	"math"
 	"os"
 	"os/exec"
+	"reflect"
 	"runtime"
 	"sort"
 	"strconv"
@@ -462,6 +467,12 @@ func TestHelp(t *testing.T) {
 	if !helpCalled {
 		t.Fatal("help was not called")
 	}
+	if !fs.HelpRequested() {
+		t.Fatal("HelpRequested false after --help")
+	}
+	if ErrHelp.Error() != "help requested" {
+		t.Fatalf("ErrHelp changed to %q", ErrHelp.Error())
+	}
 	// If we define a help flag, that should override.
 	var help bool
 	fs.PresVar(&help, "help", "help flag")
@@ -473,6 +484,9 @@ func TestHelp(t *testing.T) {
 	if helpCalled {
 		t.Fatal("help was called; should not have been for defined help flag")
 	}
+	if fs.HelpRequested() {
+		t.Fatal("HelpRequested true for defined help flag")
+	}
 }
 
 const defaultOutput = "Options:\n  -A     for bootstrapping, allow 'any' type  (Default: false)\n  --Alongflagname  disable bounds checking  (Default: false)\n  -C     a boolean defaulting to true  (Default: true)\n  -D     set relative path for local imports  (Default: \"\")\n  -E     issue 23543  (Default: \"0\")\n  -F STR  issue 23543  (Default: \"0\")\n  -I     a non-zero number  (Default: 2.7)\n  -K     a float that defaults to zero  (Default: 0)\n  -世    a present flag\nChild options:\n  -M     a multiline\n         help\n         string  (Default: \"\")\n  -N     a non-zero int  (Default: 27)\n  -O     a flag\n         multiline help string  (Default: true)\n  -Z     an int that defaults to zero  (Default: 0)\n  --世界  unicode string  (Default: \"hello\")\nNon-standard option:\n  --maxT  set timeout for dial  (Default: 0s)\n"
@@ -706,3 +720,2445 @@ func TestExitCode(t *testing.T) {
 		}
 	}
 }
+
+func TestOnFlag(t *testing.T) {
+	fs := NewFlagSet("on flag test", ContinueOnError)
+	fs.Pres("v", "verbose")
+	fs.String("name n", "", "a name", "")
+	fs.StringSlice("list", "a list", "", 0)
+	var seen []string
+	fs.SetOnFlag(func(name string, value []string) {
+		seen = append(seen, fmt.Sprintf("%s=%q", name, value))
+	})
+	if err := fs.Parse([]string{"-v", "-n", "x", "--list", "a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	want := `[v=[] n=["x"] list=["a" "b"]]`
+	if got := fmt.Sprint(seen); got != want {
+		t.Errorf("got %s; want %s", got, want)
+	}
+}
+
+func TestDefaultFromFlag(t *testing.T) {
+	fs := NewFlagSet("default from test", ContinueOnError)
+	in := fs.String("input-dir", ".", "input directory", "DIR")
+	out := fs.String("output-dir", "", "output directory", "DIR")
+	fs.SetDefaultFromFlag("output-dir", "input-dir")
+	if err := fs.Parse([]string{"--input-dir", "/data"}); err != nil {
+		t.Fatal(err)
+	}
+	if *out != "/data" {
+		t.Errorf("output-dir = %q; want %q", *out, "/data")
+	}
+
+	fs = NewFlagSet("default from test", ContinueOnError)
+	in = fs.String("input-dir", ".", "input directory", "DIR")
+	out = fs.String("output-dir", "", "output directory", "DIR")
+	fs.SetDefaultFromFlag("output-dir", "input-dir")
+	if err := fs.Parse([]string{"--output-dir", "/tmp", "--input-dir", "/data"}); err != nil {
+		t.Fatal(err)
+	}
+	if *in != "/data" || *out != "/tmp" {
+		t.Errorf("input-dir, output-dir = %q, %q; want %q, %q", *in, *out, "/data", "/tmp")
+	}
+
+	// a chain is resolved from its end, whatever the order declared
+	fs = NewFlagSet("default from test", ContinueOnError)
+	fs.String("in", "i0", "input", "X")
+	mid := fs.String("mid", "m0", "middle", "X")
+	out = fs.String("out", "o0", "output", "X")
+	fs.SetDefaultFromFlag("out", "mid")
+	fs.SetDefaultFromFlag("mid", "in")
+	if err := fs.Parse([]string{"--in", "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if *mid != "x" || *out != "x" {
+		t.Errorf("mid, out = %q, %q; want x, x", *mid, *out)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("SetDefaultFromFlag did not panic on a cycle")
+			}
+		}()
+		fs.SetOutput(Discard{})
+		fs.SetDefaultFromFlag("in", "out")
+	}()
+
+	// the default is checked as a value given on the command line
+	fs = NewFlagSet("default from test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	fs.Int("a", 0, "a", "N")
+	n := fs.Int("n", 0, "n", "N")
+	fs.MarkNonNegative("n")
+	fs.SetDefaultFromFlag("n", "a")
+	if err := fs.Parse([]string{"--a", "-5"}); err == nil || !strings.Contains(err.Error(), "must not be negative") {
+		t.Errorf("got error %v; want must not be negative", err)
+	}
+	if *n != 0 {
+		t.Errorf("n = %d; want 0", *n)
+	}
+}
+
+func TestMarkUTF8(t *testing.T) {
+	fs := NewFlagSet("utf8 test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	label := fs.String("label", "", "a label", "")
+	fs.MarkUTF8("label")
+	if err := fs.Parse([]string{"--label", "世界"}); err != nil {
+		t.Fatal(err)
+	}
+	if *label != "世界" {
+		t.Errorf("label = %q; want %q", *label, "世界")
+	}
+	err := fs.Parse([]string{"--label", "bad\xff"})
+	if err == nil {
+		t.Fatal("expected error for invalid UTF-8")
+	}
+	if !strings.Contains(err.Error(), "invalid value") || !strings.Contains(err.Error(), "UTF-8") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := fs.Set("label", []string{"\xfe"}); err == nil {
+		t.Error("expected error from Set for invalid UTF-8")
+	}
+}
+
+func TestUsageString(t *testing.T) {
+	fs := NewFlagSet("app", ContinueOnError)
+	var buf bytes.Buffer
+	fs.SetOutput(&buf)
+	fs.Title = "App, Version: 1.0"
+	fs.String("name n", "", "a name", "NAME")
+	fs.Pres("v", "verbose")
+	got := fs.UsageString()
+	want := "App, Version: 1.0\n\nUsage: app [options...]\nOptions:\n  -n, --name NAME  a name  (Default: \"\")\n  -v               verbose\n"
+	if got != want {
+		t.Errorf("got %q\n\nwant %q\n", got, want)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("UsageString wrote to output: %q", buf.String())
+	}
+}
+
+const alignedOutput = "  -a STR  short  (Default: \"x\")\n  -b    a much longer usage  (Default: 1)\n  -c    multiline\n        usage  (Default: false)\n  -d    present\n"
+
+const alignedOutputDefaults = "  -a STR  short              (Default: \"x\")\n  -b    a much longer usage  (Default: 1)\n  -c    multiline\n        usage                (Default: false)\n  -d    present\n"
+
+func TestAlignDefaults(t *testing.T) {
+	fs := NewFlagSet("align test", ContinueOnError)
+	var buf bytes.Buffer
+	fs.SetOutput(&buf)
+	fs.ShowGroupings = false
+	fs.String("a", "x", "short", "STR")
+	fs.Int("b", 1, "a much longer usage", "")
+	fs.Bool("c", false, "multiline\nusage", "")
+	fs.Pres("d", "present")
+	fs.PrintDefaults()
+	if got := buf.String(); got != alignedOutput {
+		t.Errorf("got %q\n\nwant %q\n", got, alignedOutput)
+	}
+	buf.Reset()
+	fs.SetAlignDefaults(true)
+	fs.PrintDefaults()
+	if got := buf.String(); got != alignedOutputDefaults {
+		t.Errorf("got %q\n\nwant %q\n", got, alignedOutputDefaults)
+	}
+}
+
+const wrappedOutput = "  -c, --count INT  the number of times to repeat the\n                   request before giving up on the\n                   remote server  (Default: 3)\n  -v               verbose output,\n                   multiline\n"
+
+func TestWrapUsage(t *testing.T) {
+	fs := NewFlagSet("wrap test", ContinueOnError)
+	var buf bytes.Buffer
+	fs.SetOutput(&buf)
+	fs.ShowGroupings = false
+	fs.Width = 52
+	fs.Int("count c", 3, "the number of times to repeat the request before giving up on the remote server", "INT")
+	fs.Pres("v", "verbose output,\nmultiline")
+	fs.PrintDefaults()
+	if got := buf.String(); got != wrappedOutput {
+		t.Errorf("got %q\n\nwant %q\n", got, wrappedOutput)
+	}
+	for _, l := range strings.Split(buf.String(), "\n") {
+		if strings.HasSuffix(l, "(Default: 3)") {
+			continue
+		}
+		if len(l) > fs.Width {
+			t.Errorf("line wider than %d: %q", fs.Width, l)
+		}
+	}
+}
+
+func TestBindPassthrough(t *testing.T) {
+	fs := NewFlagSet("passthrough test", ContinueOnError)
+	fs.SetAllowIntersperse(true)
+	v := fs.Pres("v", "verbose")
+	var extra []string
+	fs.BindPassthrough(&extra)
+	if err := fs.Parse([]string{"input", "-v", "--", "cmd", "--flag"}); err != nil {
+		t.Fatal(err)
+	}
+	if !*v {
+		t.Error("v flag not set")
+	}
+	if got, want := fmt.Sprint(fs.Args()), "[input]"; got != want {
+		t.Errorf("Args() = %s; want %s", got, want)
+	}
+	if got, want := fmt.Sprint(extra), "[cmd --flag]"; got != want {
+		t.Errorf("passthrough = %s; want %s", got, want)
+	}
+}
+
+func TestNameSeparator(t *testing.T) {
+	fs := NewFlagSet("separator test", ContinueOnError)
+	fs.ShowGroupings = false
+	fs.ShowDefaultVal = false
+	fs.UsageIndent = 16
+	fs.String("file f", "", "a file", "FILE")
+	fs.Pres("long", "long only")
+	fs.SetNameSeparator("|")
+	want := "  -f|--file FILE  a file\n     --long     long only\n"
+	var buf bytes.Buffer
+	fs.SetOutput(&buf)
+	fs.PrintDefaults()
+	if got := buf.String(); got != want {
+		t.Errorf("got %q\n\nwant %q\n", got, want)
+	}
+}
+
+func TestStringList(t *testing.T) {
+	fs := NewFlagSet("string list test", ContinueOnError)
+	tags := fs.StringList("tags", "tags to apply", "TAG")
+	v := fs.Pres("v", "verbose")
+	if err := fs.Parse([]string{"--tags", "a,b", "c", "-v", "--tags", "d, ,e,"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprintf("%q", *tags), `["a" "b" "c" "d" "e"]`; got != want {
+		t.Errorf("tags = %s; want %s", got, want)
+	}
+	if !*v {
+		t.Error("v flag not set")
+	}
+}
+
+func TestSetFromString(t *testing.T) {
+	fs := NewFlagSet("set from string test", ContinueOnError)
+	n := fs.Int("n", 0, "a number", "")
+	if err := fs.SetFromString("n", "42"); err != nil {
+		t.Fatal(err)
+	}
+	if *n != 42 {
+		t.Errorf("n = %d; want 42", *n)
+	}
+	if fs.NFlag() != 1 {
+		t.Errorf("NFlag() = %d; want 1", fs.NFlag())
+	}
+	err := fs.SetFromString("n", "x")
+	if err == nil || !strings.Contains(err.Error(), `invalid value "x" for parameter -n`) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := fs.SetFromString("missing", "x"); err == nil {
+		t.Error("expected error for undefined flag")
+	}
+}
+
+func TestISODuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"90s", 90 * time.Second},
+		{"PT1H30M", 90 * time.Minute},
+		{"P1DT12H", 36 * time.Hour},
+		{"P1W", 7 * 24 * time.Hour},
+		{"PT0.5S", 500 * time.Millisecond},
+		{"-PT5M", -5 * time.Minute},
+	}
+	for _, test := range tests {
+		fs := NewFlagSet("iso duration test", ContinueOnError)
+		d := fs.ISODuration("timeout", 0, "timeout", "")
+		if err := fs.Parse([]string{"--timeout", test.in}); err != nil {
+			t.Errorf("Parse(%q): %v", test.in, err)
+			continue
+		}
+		if *d != test.want {
+			t.Errorf("Parse(%q) = %v; want %v", test.in, *d, test.want)
+		}
+	}
+	for _, bad := range []string{"P", "PT", "P1H", "PT1D", "1X", "P1.5"} {
+		fs := NewFlagSet("iso duration test", ContinueOnError)
+		fs.SetOutput(Discard{})
+		fs.ISODuration("timeout", 0, "timeout", "")
+		if err := fs.Parse([]string{"--timeout", bad}); err == nil {
+			t.Errorf("Parse(%q) expected error", bad)
+		}
+	}
+}
+
+func TestPathFlags(t *testing.T) {
+	dir := t.TempDir()
+	file := dir + "/file.txt"
+	if err := os.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		define func(fs *FlagSet, p *string)
+		arg    string
+		ok     bool
+	}{
+		{func(fs *FlagSet, p *string) { fs.ExistingFileVar(p, "path", "", "", "") }, file, true},
+		{func(fs *FlagSet, p *string) { fs.ExistingFileVar(p, "path", "", "", "") }, dir, false},
+		{func(fs *FlagSet, p *string) { fs.ExistingFileVar(p, "path", "", "", "") }, dir + "/missing", false},
+		{func(fs *FlagSet, p *string) { fs.ExistingDirVar(p, "path", "", "", "") }, dir, true},
+		{func(fs *FlagSet, p *string) { fs.ExistingDirVar(p, "path", "", "", "") }, file, false},
+		{func(fs *FlagSet, p *string) { fs.NewFileVar(p, "path", "", "", "") }, dir + "/new.txt", true},
+		{func(fs *FlagSet, p *string) { fs.NewFileVar(p, "path", "", "", "") }, dir + "/missing/new.txt", false},
+		{func(fs *FlagSet, p *string) { fs.NewFileVar(p, "path", "", "", "") }, dir, false},
+	}
+	for _, test := range tests {
+		fs := NewFlagSet("path test", ContinueOnError)
+		fs.SetOutput(Discard{})
+		var p string
+		test.define(fs, &p)
+		err := fs.Parse([]string{"--path", test.arg})
+		if test.ok && (err != nil || p != test.arg) {
+			t.Errorf("Parse(%q) = %q, %v; want success", test.arg, p, err)
+		}
+		if !test.ok && err == nil {
+			t.Errorf("Parse(%q) expected error", test.arg)
+		}
+	}
+}
+
+func TestResetCommandLine(t *testing.T) {
+	ResetForTesting(nil)
+	Bool("reset", false, "defined before reset", "")
+	ResetCommandLine()
+	if Lookup("reset") != nil {
+		t.Error("flag still defined after ResetCommandLine")
+	}
+	if CommandLine.ErrorHandling() != ExitOnError {
+		t.Errorf("ErrorHandling() = %v; want ExitOnError", CommandLine.ErrorHandling())
+	}
+	Bool("reset", false, "defined after reset", "")
+	ResetForTesting(nil)
+}
+
+func TestFlagFuncIndexed(t *testing.T) {
+	fs := NewFlagSet("func indexed test", ContinueOnError)
+	var steps []string
+	fs.FlagFuncIndexed("step", "a pipeline step", "NAME ARG", 2, func(i int, vals []string) error {
+		steps = append(steps, fmt.Sprintf("%d:%s=%s", i, vals[0], vals[1]))
+		return nil
+	})
+	if err := fs.Parse([]string{"--step", "grep", "foo", "--step", "sort", "-r", "input"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(steps), "[0:grep=foo 1:sort=-r]"; got != want {
+		t.Errorf("steps = %s; want %s", got, want)
+	}
+	if got, want := fmt.Sprint(fs.Args()), "[input]"; got != want {
+		t.Errorf("Args() = %s; want %s", got, want)
+	}
+
+	// an error from a flag taking no values fails the parse too
+	fs.SetOutput(Discard{})
+	fs.FlagFuncIndexed("once", "only once", "", 0, func(i int, vals []string) error {
+		if i > 0 {
+			return fmt.Errorf("given more than once")
+		}
+		return nil
+	})
+	err := fs.Parse([]string{"--once", "--once"})
+	if pe, ok := err.(*ParseError); !ok || pe.Reason != ReasonInvalid ||
+		pe.Error() != "invalid value for parameter --once: given more than once" {
+		t.Errorf("got error %v; want invalid value for --once", err)
+	}
+}
+
+func TestBuiltinHelp(t *testing.T) {
+	fs := NewFlagSet("builtin help test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	fs.SetBuiltinHelp(false)
+	err := fs.Parse([]string{"-h"})
+	if err == nil || err == ErrHelp {
+		t.Fatalf("expected unknown flag error; got %v", err)
+	}
+	if fs.HelpRequested() {
+		t.Error("HelpRequested true with built-in help disabled")
+	}
+	fs.SetBuiltinHelp(true)
+	if err := fs.Parse([]string{"--help"}); err != ErrHelp {
+		t.Errorf("expected ErrHelp; got %v", err)
+	}
+}
+
+func TestPrintSummary(t *testing.T) {
+	fs := NewFlagSet("summary test", ContinueOnError)
+	fs.String("name n", "", "a name\nfor the thing", "NAME")
+	fs.Pres("verbose", "print more about what is being done while running")
+	fs.Width = 40
+	var buf bytes.Buffer
+	fs.PrintSummary(&buf)
+	want := "  -n, --name  a name for the thing\n  --verbose   print more about what is\n              being done while running\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q\n\nwant %q\n", got, want)
+	}
+}
+
+func TestMarkImplies(t *testing.T) {
+	for _, test := range []struct {
+		args    []string
+		verbose bool
+		level   string
+	}{
+		{[]string{}, false, "info"},
+		{[]string{"--debug"}, true, "debug"},
+		{[]string{"--log-level", "warn", "--debug"}, true, "warn"},
+		{[]string{"--debug", "--log-level", "warn"}, true, "warn"},
+	} {
+		fs := NewFlagSet("implies test", ContinueOnError)
+		fs.Pres("debug", "debug mode")
+		verbose := fs.Pres("verbose", "verbose output")
+		level := fs.String("log-level", "info", "log level", "LEVEL")
+		fs.MarkImplies("debug", map[string][]string{
+			"verbose":   {},
+			"log-level": {"debug"},
+		})
+		if err := fs.Parse(test.args); err != nil {
+			t.Fatal(err)
+		}
+		if *verbose != test.verbose || *level != test.level {
+			t.Errorf("Parse(%q): verbose, log-level = %v, %q; want %v, %q",
+				test.args, *verbose, *level, test.verbose, test.level)
+		}
+	}
+}
+
+func TestDumpFlags(t *testing.T) {
+	fs := NewFlagSet("dump flags test", ContinueOnError)
+	var buf bytes.Buffer
+	fs.SetOutput(&buf)
+	fs.String("name n", "", "a name", "")
+	fs.Int("count", 1, "a count", "")
+	fs.EnableDumpFlags()
+	if err := fs.Parse([]string{"--dump-flags", "-n", "x"}); err != ErrDumpFlags {
+		t.Fatalf("expected ErrDumpFlags; got %v", err)
+	}
+	want := "--count: 1\n-n, --name: x\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+func TestEnvPrefix(t *testing.T) {
+	t.Setenv("APP_MAX_CONN", "7")
+	t.Setenv("APP_NAME", "env")
+	t.Setenv("APP_VERBOSE", "true")
+	t.Setenv("OTHER_PORT", "8080")
+	fs := NewFlagSet("env test", ContinueOnError)
+	maxConn := fs.Int("max-conn", 1, "max connections", "")
+	name := fs.String("name", "", "a name", "")
+	verbose := fs.Pres("verbose", "verbose output")
+	port := fs.Int("port", 80, "port", "")
+	fs.SetEnvPrefix("app")
+	fs.SetEnv("port", "OTHER_PORT")
+	if err := fs.Parse([]string{"--name", "cli"}); err != nil {
+		t.Fatal(err)
+	}
+	if *maxConn != 7 || *name != "cli" || !*verbose || *port != 8080 {
+		t.Errorf("max-conn, name, verbose, port = %d, %q, %v, %d; want 7, \"cli\", true, 8080",
+			*maxConn, *name, *verbose, *port)
+	}
+}
+
+func TestFuncGetter(t *testing.T) {
+	fs := NewFlagSet("func getter test", ContinueOnError)
+	fs.FlagFunc("pair", "a pair", "A B", 2, func([]string) error { return nil })
+	g, ok := fs.Lookup("pair").Value.(Getter)
+	if !ok {
+		t.Fatalf("func value does not satisfy Getter: %T", fs.Lookup("pair").Value)
+	}
+	if v := g.Get().([]string); v != nil {
+		t.Errorf("Get() = %q before Parse; want nil", v)
+	}
+	if err := fs.Parse([]string{"--pair", "a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(g.Get()), "[a b]"; got != want {
+		t.Errorf("Get() = %s; want %s", got, want)
+	}
+}
+
+func TestFlagFuncDefault(t *testing.T) {
+	fs := NewFlagSet("func default test", ContinueOnError)
+	fs.ShowGroupings = false
+	fs.UsageIndent = 14
+	fs.FlagFuncDefault("ip", "address to bind", "ADDR", 1, "none", func([]string) error { return nil })
+	fs.FlagFunc("dns", "dns server", "ADDR", 1, func([]string) error { return nil })
+	var buf bytes.Buffer
+	fs.SetOutput(&buf)
+	fs.PrintDefaults()
+	want := "  --dns ADDR  dns server  (Default: \"\")\n  --ip ADDR   address to bind  (Default: none)\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q\n\nwant %q\n", got, want)
+	}
+}
+
+func TestParseMore(t *testing.T) {
+	fs := NewFlagSet("parse more test", ContinueOnError)
+	v := fs.Pres("v", "verbose")
+	name := fs.String("name", "", "a name", "")
+	if err := fs.Parse([]string{"-v", "first"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.ParseMore([]string{"--name", "x", "second"}); err != nil {
+		t.Fatal(err)
+	}
+	if !*v || *name != "x" {
+		t.Errorf("v, name = %v, %q; want true, \"x\"", *v, *name)
+	}
+	if got, want := fmt.Sprint(fs.Args()), "[first second]"; got != want {
+		t.Errorf("Args() = %s; want %s", got, want)
+	}
+	if fs.NFlag() != 2 {
+		t.Errorf("NFlag() = %d; want 2", fs.NFlag())
+	}
+}
+
+func TestParseUntilOperand(t *testing.T) {
+	for _, test := range []struct {
+		args     []string
+		consumed int
+		rest     string
+	}{
+		{[]string{"-v", "--name", "x", "sub", "--flag"}, 3, "[sub --flag]"},
+		{[]string{"-v", "--", "-sub"}, 2, "[-sub]"},
+		{[]string{"sub", "-v"}, 0, "[sub -v]"},
+		{[]string{"-v"}, 1, "[]"},
+	} {
+		fs := NewFlagSet("parse until operand test", ContinueOnError)
+		fs.SetAllowIntersperse(true)
+		fs.Pres("v", "verbose")
+		fs.String("name", "", "a name", "")
+		consumed, err := fs.ParseUntilOperand(test.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if consumed != test.consumed || fmt.Sprint(fs.Args()) != test.rest {
+			t.Errorf("ParseUntilOperand(%q) = %d, %v; want %d, %s",
+				test.args, consumed, fs.Args(), test.consumed, test.rest)
+		}
+	}
+}
+
+func TestDashValue(t *testing.T) {
+	fs := NewFlagSet("dash test", ContinueOnError)
+	out := fs.String("output o", "", "output file", "")
+	in := fs.StringSlice("input", "input files", "", 0)
+	if err := fs.Parse([]string{"-o", "-", "--input", "a", "-", "b", "-"}); err != nil {
+		t.Fatal(err)
+	}
+	if !IsStdinDash(*out) {
+		t.Errorf("output = %q; want -", *out)
+	}
+	if got, want := fmt.Sprint(*in), "[a - b -]"; got != want {
+		t.Errorf("input = %s; want %s", got, want)
+	}
+	fs = NewFlagSet("dash test", ContinueOnError)
+	fs.Pres("v", "verbose")
+	if err := fs.Parse([]string{"-v", "-"}); err != nil {
+		t.Fatal(err)
+	}
+	if fs.NArg() != 1 || !IsStdinDash(fs.Arg(0)) {
+		t.Errorf("Args() = %q; want [-]", fs.Args())
+	}
+}
+
+func TestRawArgs(t *testing.T) {
+	fs := NewFlagSet("raw args test", ContinueOnError)
+	fs.Pres("v", "verbose")
+	args := []string{"-v", "input"}
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	raw := fs.RawArgs()
+	if got, want := fmt.Sprint(raw), "[-v input]"; got != want {
+		t.Errorf("RawArgs() = %s; want %s", got, want)
+	}
+	raw[0] = "changed"
+	if fs.RawArgs()[0] != "-v" {
+		t.Error("RawArgs() did not return a copy")
+	}
+}
+
+func TestFlagBuilder(t *testing.T) {
+	fs := NewFlagSet("builder test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	var timeout time.Duration
+	var verbose bool
+	var secret string
+	fs.Flag("timeout t").Duration(30 * time.Second).Usage("how long to wait").Type("DUR").Register(&timeout)
+	fs.Flag("verbose v").Pres().Usage("verbose output").Register(&verbose)
+	fs.Flag("secret").Usage("not shown").Hidden().Required().Register(&secret)
+	if timeout != 30*time.Second {
+		t.Errorf("timeout default = %v; want 30s", timeout)
+	}
+	if err := fs.Parse([]string{"-t", "1m", "-v"}); err == nil || !strings.Contains(err.Error(), "required") {
+		t.Errorf("expected required error; got %v", err)
+	}
+	if err := fs.Parse([]string{"-t", "1m", "-v", "--secret", "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if timeout != time.Minute || !verbose || secret != "x" {
+		t.Errorf("timeout, verbose, secret = %v, %v, %q; want 1m0s, true, \"x\"", timeout, verbose, secret)
+	}
+	if usage := fs.UsageString(); strings.Contains(usage, "secret") || !strings.Contains(usage, "-t, --timeout DUR") {
+		t.Errorf("unexpected usage: %q", usage)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for mismatched default")
+		}
+	}()
+	var n int
+	fs.Flag("n").String("x").Register(&n)
+}
+
+func TestUnicodeNames(t *testing.T) {
+	fs := NewFlagSet("unicode names test", ContinueOnError)
+	var buf bytes.Buffer
+	fs.SetOutput(&buf)
+	fs.ShowGroupings = false
+	fs.ShowDefaultVal = false
+	fs.UsageIndent = 14
+	world := fs.String("世 世界", "", "one rune short name", "")
+	e := fs.Pres("é", "two byte short name")
+	a := fs.Pres("a", "ascii short name")
+	if names := fs.Lookup("世").Name; len(names) != 2 || names[0] != "世界" || names[1] != "世" {
+		t.Errorf("names = %q; want [世界 世]", names)
+	}
+	if err := fs.Parse([]string{"-éa", "-世", "x"}); err != nil {
+		t.Fatal(err)
+	}
+	if !*e || !*a || *world != "x" {
+		t.Errorf("é, a, 世 = %v, %v, %q; want true, true, \"x\"", *e, *a, *world)
+	}
+	if err := fs.Parse([]string{"--世界=y"}); err != nil || *world != "y" {
+		t.Errorf("--世界=y gave %q, %v", *world, err)
+	}
+	fs.PrintDefaults()
+	want := "  -a          ascii short name\n  -é          two byte short name\n  -世, --世界  one rune short name\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q\n\nwant %q\n", got, want)
+	}
+	buf.Reset()
+	fs.Parse([]string{"-ü"})
+	if got := buf.String(); !strings.HasPrefix(got, "parameter provided but not defined: -ü\n") {
+		t.Errorf("unexpected error output %q", got)
+	}
+}
+
+func TestClearToken(t *testing.T) {
+	fs := NewFlagSet("clear token test", ContinueOnError)
+	tags := fs.StringSlice("tags", "tags", "", 0)
+	hosts := fs.StringSlice("host", "a host", "", 1)
+	fs.SetClearToken("none")
+	args := []string{"--tags", "a", "b", "--host", "x", "--tags", "none", "c", "--host", "none"}
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(*tags), "[c]"; got != want {
+		t.Errorf("tags = %s; want %s", got, want)
+	}
+	if len(*hosts) != 0 {
+		t.Errorf("hosts = %q; want empty", *hosts)
+	}
+
+	// an empty token, given attached, and fixed-count flags
+	fs = NewFlagSet("empty clear token test", ContinueOnError)
+	tags = fs.StringSlice("tags", "tags", "", 0)
+	pairs := fs.StringSlice("pair", "a pair", "", 2)
+	var seen []string
+	fs.SetOnFlag(func(name string, value []string) { seen = append(seen, name) })
+	fs.SetClearToken("")
+	args = []string{"--tags", "a", "--pair", "x", "y", "--tags=", "--pair", "", "--tags=", "b"}
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(*tags), "[b]"; got != want {
+		t.Errorf("tags = %s; want %s", got, want)
+	}
+	if len(*pairs) != 0 {
+		t.Errorf("pairs = %q; want empty", *pairs)
+	}
+	if got, want := fmt.Sprint(seen), "[tags pair tags]"; got != want {
+		t.Errorf("flags seen = %s; want %s", got, want)
+	}
+	fs.UnsetClearToken()
+	if err := fs.Parse([]string{"--pair", "", "z"}); err != nil || fmt.Sprint(*pairs) != `[ z]` {
+		t.Errorf("pairs = %q, %v; want [\"\" \"z\"]", *pairs, err)
+	}
+}
+
+func TestAnnotation(t *testing.T) {
+	fs := NewFlagSet("annotation test", ContinueOnError)
+	fs.Int("level l", 1, "a level", "")
+	fs.SetAnnotation("level", "widget", "slider")
+	if v, ok := fs.Annotation("l", "widget"); !ok || v != "slider" {
+		t.Errorf("Annotation(l, widget) = %q, %v; want \"slider\", true", v, ok)
+	}
+	if _, ok := fs.Annotation("level", "category"); ok {
+		t.Error("Annotation(level, category) found; want none")
+	}
+	if _, ok := fs.Annotation("missing", "widget"); ok {
+		t.Error("Annotation(missing, widget) found; want none")
+	}
+}
+
+func TestPrintDefaultsGroupOrder(t *testing.T) {
+	fs := NewFlagSet("group order test", ContinueOnError)
+	fs.ShowDefaultVal = false
+	fs.UsageIndent = 14
+	fs.GroupingSet("Second")
+	fs.Pres("zeta", "z")
+	fs.Pres("alpha a", "a")
+	fs.GroupingSet("First")
+	fs.Pres("yank", "y")
+	fs.Pres("beta", "b")
+	fs.GroupingSet("Second")
+	fs.Pres("mu", "m")
+	want := "Second options:\n  -a, --alpha  a\n      --mu    m\n      --zeta  z\nFirst options:\n      --beta  b\n      --yank  y\n"
+	for i := 0; i < 2; i++ {
+		var buf bytes.Buffer
+		fs.SetOutput(&buf)
+		fs.PrintDefaults()
+		if got := buf.String(); got != want {
+			t.Errorf("got %q\n\nwant %q\n", got, want)
+		}
+	}
+}
+
+func TestLintUsage(t *testing.T) {
+	fs := NewFlagSet("lint test", ContinueOnError)
+	fs.String("name n", "", "a name", "NAME")
+	fs.Pres("verbose", "verbose output")
+	fs.Int("count", 0, "a count", "")
+	fs.Pres("quiet", " ")
+	fs.Flag("secret").Hidden().Register(new(string))
+	var got []string
+	for _, err := range fs.LintUsage() {
+		got = append(got, err.Error())
+	}
+	want := "[parameter --count has no type expected parameter --quiet has no usage]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("LintUsage() = %s; want %s", got, want)
+	}
+}
+
+func TestDuplicateTerminator(t *testing.T) {
+	args := []string{"-v", "--", "--", "x", "--"}
+	fs := NewFlagSet("terminator test", ContinueOnError)
+	fs.SetAllowIntersperse(true)
+	fs.Pres("v", "verbose")
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(fs.Args()), "[-- x --]"; got != want {
+		t.Errorf("Args() = %s; want %s", got, want)
+	}
+
+	var extra []string
+	fs.BindPassthrough(&extra)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(extra), "[-- x --]"; got != want || fs.NArg() != 0 {
+		t.Errorf("passthrough, Args() = %s, %q; want %s, []", got, fs.Args(), want)
+	}
+
+	consumed, err := fs.ParseUntilOperand(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(fs.Args()), "[-- x --]"; got != want || consumed != 2 {
+		t.Errorf("ParseUntilOperand = %d, %s; want 2, %s", consumed, got, want)
+	}
+}
+
+func TestSyncValue(t *testing.T) {
+	fs := NewFlagSet("sync value test", ContinueOnError)
+	items := fs.StringSlice("item", "items", "", 1)
+	flag := fs.Lookup("item")
+	flag.Value = SyncValue(flag.Value)
+	done := make(chan bool)
+	for i := 0; i < 10; i++ {
+		go func(i int) {
+			for j := 0; j < 100; j++ {
+				flag.Value.Set([]string{strconv.Itoa(i)})
+			}
+			done <- true
+		}(i)
+	}
+	for i := 0; i < 10; i++ {
+		<-done
+	}
+	if len(*items) != 1000 {
+		t.Errorf("len(items) = %d; want 1000", len(*items))
+	}
+	if got := flag.Value.(Getter).Get().([]string); len(got) != 1000 {
+		t.Errorf("len(Get()) = %d; want 1000", len(got))
+	}
+
+	// the wrapped values are still treated as their kind
+	fs = NewFlagSet("sync value kinds test", ContinueOnError)
+	tags := fs.StringSlice("tags", "tags", "TAG", -1)
+	verbose := fs.Pres("verbose", "verbose output")
+	for _, name := range []string{"tags", "verbose"} {
+		flag := fs.Lookup(name)
+		flag.Value = SyncValue(flag.Value)
+	}
+	fs.Var(SyncValue(new(noneDefault)), "delay", "delay", "DURATION", 1)
+	if got := fs.Lookup("delay").DefValue; got != "none" {
+		t.Errorf("DefValue = %q; want none", got)
+	}
+	fs.SetClearToken("none")
+	if err := fs.Parse([]string{"--tags", "a", "--verbose", "--tags", "none", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(*tags) != "[b]" || !*verbose {
+		t.Errorf("got tags %q verbose %v; want [b] true", *tags, *verbose)
+	}
+	lines := strings.Join(fs.DefaultLines(), "\n")
+	if strings.Contains(lines, "Default: [") || strings.Contains(lines, "Default: false") {
+		t.Errorf("defaults shown for a list or present flag:\n%s", lines)
+	}
+}
+
+// noneDefault is a Value showing its default as "none".
+type noneDefault string
+
+func (n *noneDefault) Set(v []string) error  { *n = noneDefault(v[0]); return nil }
+func (n *noneDefault) String() string        { return string(*n) }
+func (n *noneDefault) DefaultString() string { return "none" }
+
+func TestEchoArgsOnError(t *testing.T) {
+	fs := NewFlagSet("prog", ContinueOnError)
+	fs.Usage = func() {}
+	var buf bytes.Buffer
+	fs.SetOutput(&buf)
+	fs.Int("count", 0, "a count", "")
+	fs.SetEchoArgsOnError(true)
+	fs.Parse([]string{"--count", "x y"})
+	want := "while parsing: prog --count \"x y\"\ninvalid value \"x y\" for parameter --count: strconv.ParseInt: parsing \"x y\": invalid syntax\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+func TestDeprecatedAlias(t *testing.T) {
+	fs := NewFlagSet("deprecated alias test", ContinueOnError)
+	var buf bytes.Buffer
+	fs.SetOutput(&buf)
+	out := fs.String("output-dir", "", "output directory", "DIR")
+	fs.DeprecatedAlias("outdir", "output-dir", "will be removed in 2.0")
+	if err := fs.Parse([]string{"--output-dir", "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("unexpected warning for canonical name: %q", buf.String())
+	}
+	if err := fs.Parse([]string{"--outdir", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if *out != "b" {
+		t.Errorf("output-dir = %q; want \"b\"", *out)
+	}
+	want := "parameter --outdir is deprecated, use --output-dir instead: will be removed in 2.0\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+	if strings.Contains(fs.UsageString(), "outdir") {
+		t.Error("deprecated alias shown in help")
+	}
+}
+
+func TestSliceGetters(t *testing.T) {
+	fs := NewFlagSet("slice getter test", ContinueOnError)
+	fs.StringSlice("slice", "a slice", "", 0)
+	fs.StringList("list", "a list", "")
+	if err := fs.Parse([]string{"--slice", "a", "b", "--list", "c,d"}); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"slice": "[a b]", "list": "[c d]"} {
+		g, ok := fs.Lookup(name).Value.(Getter)
+		if !ok {
+			t.Errorf("%s: value does not satisfy Getter: %T", name, fs.Lookup(name).Value)
+			continue
+		}
+		v, ok := g.Get().([]string)
+		if !ok {
+			t.Errorf("%s: Get() = %T; want []string", name, g.Get())
+		} else if fmt.Sprint(v) != want {
+			t.Errorf("%s: Get() = %v; want %s", name, v, want)
+		}
+	}
+}
+
+func TestSetErrorOutput(t *testing.T) {
+	fs := NewFlagSet("error output test", ContinueOnError)
+	var out, errOut bytes.Buffer
+	fs.SetOutput(&out)
+	if fs.ErrorOutput() != &out {
+		t.Error("ErrorOutput() does not default to Output()")
+	}
+	fs.SetErrorOutput(&errOut)
+	fs.Pres("v", "verbose")
+	fs.Parse([]string{"-x"})
+	if got, want := errOut.String(), "parameter provided but not defined: -x\n"; got != want {
+		t.Errorf("error output = %q; want %q", got, want)
+	}
+	if got := out.String(); strings.Contains(got, "not defined") || !strings.Contains(got, "verbose") {
+		t.Errorf("unexpected usage output %q", got)
+	}
+}
+
+func TestLenientBool(t *testing.T) {
+	for in, want := range map[string]bool{
+		"yes": true, "ON": true, "Enabled": true, "t": true, "1": true,
+		"no": false, "Off": false, "DISABLED": false, "false": false, "0": false,
+	} {
+		fs := NewFlagSet("lenient bool test", ContinueOnError)
+		b := fs.LenientBool("tls", !want, "enable tls", "BOOL")
+		if err := fs.Parse([]string{"--tls", in}); err != nil {
+			t.Errorf("Parse(%q): %v", in, err)
+		} else if *b != want {
+			t.Errorf("Parse(%q) = %v; want %v", in, *b, want)
+		}
+	}
+	fs := NewFlagSet("lenient bool test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	fs.LenientBool("tls", false, "enable tls", "BOOL")
+	if err := fs.Parse([]string{"--tls", "maybe"}); err == nil {
+		t.Error("expected error for maybe")
+	}
+	fs = NewFlagSet("strict bool test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	fs.Bool("tls", false, "enable tls", "BOOL")
+	if err := fs.Parse([]string{"--tls", "yes"}); err == nil {
+		t.Error("expected error for yes with strict Bool")
+	}
+}
+
+func TestDefineExpansion(t *testing.T) {
+	fs := NewFlagSet("expansion test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	inline := fs.Pres("inline", "inline functions")
+	level := fs.Int("level", 0, "optimization level", "N")
+	fs.DefineExpansion("O2", []string{"--inline", "--level", "2"})
+	fs.DefineExpansion("fast", []string{"-O2"})
+	if err := fs.Parse([]string{"-O2", "file"}); err != nil {
+		t.Fatal(err)
+	}
+	if !*inline || *level != 2 {
+		t.Errorf("-O2 gave inline=%v level=%d; want true 2", *inline, *level)
+	}
+	if args := fs.Args(); len(args) != 1 || args[0] != "file" {
+		t.Errorf("Args() = %q; want [file]", args)
+	}
+
+	*inline, *level = false, 0
+	if err := fs.Parse([]string{"--fast", "--level", "3"}); err != nil {
+		t.Fatal(err)
+	}
+	if !*inline || *level != 3 {
+		t.Errorf("--fast --level 3 gave inline=%v level=%d; want true 3", *inline, *level)
+	}
+	if err := fs.Parse([]string{"--fast=x"}); err == nil {
+		t.Error("expected error for value after expansion")
+	}
+}
+
+func TestArgBounds(t *testing.T) {
+	fs := NewFlagSet("arg bounds test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	if min, max := fs.NArgExpected(); min != 0 || max != -1 {
+		t.Errorf("NArgExpected() = %d, %d; want 0, -1", min, max)
+	}
+	fs.SetArgBounds(1, 2)
+	if min, max := fs.NArgExpected(); min != 1 || max != 2 {
+		t.Errorf("NArgExpected() = %d, %d; want 1, 2", min, max)
+	}
+	for _, test := range []struct {
+		args []string
+		ok   bool
+	}{
+		{[]string{}, false},
+		{[]string{"a"}, true},
+		{[]string{"a", "b"}, true},
+		{[]string{"a", "b", "c"}, false},
+	} {
+		if err := fs.Parse(test.args); (err == nil) != test.ok {
+			t.Errorf("Parse(%q) error = %v; want ok %v", test.args, err, test.ok)
+		}
+	}
+	fs.SetArgBounds(1, -1)
+	if err := fs.Parse([]string{"a", "b", "c"}); err != nil {
+		t.Errorf("unbounded max: %v", err)
+	}
+}
+
+func TestBoolDisplay(t *testing.T) {
+	fs := NewFlagSet("bool display test", ContinueOnError)
+	fs.ShowDefaultVal = true
+	fs.Bool("color", false, "use color", "BOOL")
+	fs.LenientBool("tls", true, "use tls", "BOOL")
+	fs.SetBoolDisplay("on", "off")
+	var buf bytes.Buffer
+	fs.SetOutput(&buf)
+	fs.PrintDefaults()
+	if got := buf.String(); !strings.Contains(got, "(Default: off)") || !strings.Contains(got, "(Default: on)") {
+		t.Errorf("expected on/off defaults, got:\n%s", got)
+	}
+	if err := fs.Parse([]string{"--color", "true"}); err != nil {
+		t.Errorf("parsing is unchanged: %v", err)
+	}
+}
+
+func TestParseHandlingHelp(t *testing.T) {
+	fs := NewFlagSet("help handling test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	fs.Int("n", 0, "a number", "N")
+	if help, err := fs.ParseHandlingHelp([]string{"--help"}); !help || err != nil {
+		t.Errorf("--help gave %v, %v; want true, nil", help, err)
+	}
+	if help, err := fs.ParseHandlingHelp([]string{"-n", "x"}); help || err == nil {
+		t.Errorf("-n x gave %v, %v; want false and an error", help, err)
+	}
+	if help, err := fs.ParseHandlingHelp([]string{"-n", "1"}); help || err != nil {
+		t.Errorf("-n 1 gave %v, %v; want false, nil", help, err)
+	}
+}
+
+func TestOptionalInt(t *testing.T) {
+	for _, test := range []struct {
+		args  []string
+		want  int
+		nargs int
+	}{
+		{[]string{}, 0, 0},
+		{[]string{"--compress"}, 6, 0},
+		{[]string{"--compress", "9"}, 9, 0},
+		{[]string{"--compress=3"}, 3, 0},
+		{[]string{"-c2"}, 2, 0},
+		{[]string{"-cv"}, 6, 0},
+		{[]string{"--compress", "file"}, 6, 1},
+		{[]string{"-c", "file"}, 6, 1},
+	} {
+		fs := NewFlagSet("optional int test", ContinueOnError)
+		fs.SetOutput(Discard{})
+		fs.Pres("v", "verbose")
+		c := fs.OptionalInt("c compress", 6, 0, "compression level", "[LEVEL]")
+		if err := fs.Parse(test.args); err != nil {
+			t.Errorf("Parse(%q): %v", test.args, err)
+			continue
+		}
+		if *c != test.want || fs.NArg() != test.nargs {
+			t.Errorf("Parse(%q) gave %d with %d args; want %d with %d", test.args, *c, fs.NArg(), test.want, test.nargs)
+		}
+	}
+	fs := NewFlagSet("optional int test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	fs.OptionalInt("compress", 6, 0, "compression level", "[LEVEL]")
+	if err := fs.Parse([]string{"--compress=x"}); err == nil {
+		t.Error("expected error for --compress=x")
+	}
+
+
+	// a failure for the flag given alone has no value to show
+	var level int
+	fs.OptionalIntVar(&level, "level", -1, 0, "level", "[N]")
+	fs.MarkNonNegative("level")
+	err := fs.Parse([]string{"--level"})
+	if err == nil || err.Error() != "invalid value for parameter --level: must not be negative" {
+		t.Errorf("got error %v; want invalid value for --level", err)
+	}
+	if level != 0 {
+		t.Errorf("level = %d; want 0", level)
+	}
+}
+
+func TestDefaultLines(t *testing.T) {
+	fs := NewFlagSet("default lines test", ContinueOnError)
+	fs.Pres("v verbose", "be verbose")
+	fs.String("name", "", "the name\nto use", "NAME")
+	lines := fs.DefaultLines()
+	if len(lines) != 3 {
+		t.Fatalf("DefaultLines() gave %d entries; want 3: %q", len(lines), lines)
+	}
+	if lines[0] != "Options:" {
+		t.Errorf("entry 0 = %q; want grouping header", lines[0])
+	}
+	if !strings.Contains(lines[1], "--name") || !strings.Contains(lines[1], "\n") {
+		t.Errorf("entry 1 = %q; want multi-line name entry", lines[1])
+	}
+	if !strings.Contains(lines[2], "--verbose") || strings.Contains(lines[2], "\n") {
+		t.Errorf("entry 2 = %q", lines[2])
+	}
+	var buf bytes.Buffer
+	fs.SetOutput(&buf)
+	fs.PrintDefaults()
+	if got, want := buf.String(), strings.Join(lines, "\n")+"\n"; got != want {
+		t.Errorf("PrintDefaults() = %q; want %q", got, want)
+	}
+}
+
+func TestANSIWidth(t *testing.T) {
+	fs := NewFlagSet("ansi width test", ContinueOnError)
+	fs.ShowGroupings = false
+	fs.String("plain", "", "plain usage", "NAME")
+	fs.String("color", "", "color usage", "\x1b[1mNAME\x1b[0m")
+	lines := fs.DefaultLines()
+	if len(lines) != 2 {
+		t.Fatalf("DefaultLines() = %q", lines)
+	}
+	strip := strings.NewReplacer("\x1b[1m", "", "\x1b[0m", "")
+	var cols []int
+	for _, l := range lines {
+		cols = append(cols, strings.Index(strip.Replace(l), " usage")-5)
+	}
+	if cols[0] != cols[1] {
+		t.Errorf("usage columns differ, %d and %d:\n%s", cols[0], cols[1], strings.Join(lines, "\n"))
+	}
+}
+
+func TestNoPositional(t *testing.T) {
+	fs := NewFlagSet("no positional test", ContinueOnError)
+	var buf bytes.Buffer
+	fs.SetOutput(&buf)
+	fs.SetNoPositional(true)
+	fs.Pres("verbose", "be verbose")
+	if err := fs.Parse([]string{"--verbose"}); err != nil {
+		t.Errorf("flags only: %v", err)
+	}
+	err := fs.Parse([]string{"--verbose", "typo"})
+	if err == nil || err.Error() != "unexpected argument: typo" {
+		t.Errorf("got error %v; want unexpected argument: typo", err)
+	}
+}
+
+func TestOrderedActuals(t *testing.T) {
+	fs := NewFlagSet("ordered test", ContinueOnError)
+	fs.RecordOrder(true)
+	fs.StringSlice("i input", "input file", "FILE", 1)
+	fs.String("c codec", "", "codec", "NAME")
+	fs.Pres("y", "overwrite")
+	if err := fs.Parse([]string{"-i", "in1", "-c", "copy", "-y", "--input", "in2"}); err != nil {
+		t.Fatal(err)
+	}
+	want := []Occurrence{
+		{"i", []string{"in1"}},
+		{"c", []string{"copy"}},
+		{"y", []string{}},
+		{"input", []string{"in2"}},
+	}
+	if got := fs.OrderedActuals(); !reflect.DeepEqual(got, want) {
+		t.Errorf("OrderedActuals() = %q; want %q", got, want)
+	}
+	got := fs.OrderedActuals()
+	got[0].Value[0] = "changed"
+	got[1] = Occurrence{}
+	if got := fs.OrderedActuals(); !reflect.DeepEqual(got, want) {
+		t.Errorf("OrderedActuals() after changing a copy = %q; want %q", got, want)
+	}
+	if err := fs.Parse([]string{"-y"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := fs.OrderedActuals(); len(got) != 1 {
+		t.Errorf("OrderedActuals() after second Parse = %q; want only -y", got)
+	}
+}
+
+func TestLogLevel(t *testing.T) {
+	fs := NewFlagSet("log level test", ContinueOnError)
+	var buf bytes.Buffer
+	fs.SetOutput(&buf)
+	fs.ShowDefaultVal = true
+	level := fs.LogLevel("log-level", InfoLevel, "logging level", "LEVEL")
+	if err := fs.Parse([]string{"--log-level", "WARN"}); err != nil {
+		t.Fatal(err)
+	}
+	if *level != WarnLevel || *level < InfoLevel || *level >= ErrorLevel {
+		t.Errorf("level = %d; want WarnLevel", *level)
+	}
+	if got := fs.Lookup("log-level").Value.String(); got != "warn" {
+		t.Errorf("String() = %q; want warn", got)
+	}
+	if !strings.Contains(fs.UsageString(), "(Default: info)") {
+		t.Errorf("expected default of info in:\n%s", fs.UsageString())
+	}
+	buf.Reset()
+	if err := fs.Parse([]string{"--log-level", "loud"}); err == nil {
+		t.Error("expected error for unknown level")
+	} else if !strings.Contains(buf.String(), "debug, info, warn, error") {
+		t.Errorf("error does not list the levels: %q", buf.String())
+	}
+}
+
+func TestWriteFishCompletion(t *testing.T) {
+	fs := NewFlagSet("fish test", ContinueOnError)
+	fs.Pres("v verbose", "be verbose")
+	fs.String("o", "", "write to the\nuser's file", "FILE")
+	fs.Pres("secret", "not shown")
+	fs.Lookup("secret").Hidden = true
+	var buf bytes.Buffer
+	fs.WriteFishCompletion(&buf, "prog")
+	want := "complete -c prog -s o -r -d 'write to the user\\'s file'\n" +
+		"complete -c prog -s v -l verbose -d 'be verbose'\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	fs := NewFlagSet("middleware test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	name := fs.String("name", "", "a name", "NAME")
+	fs.StringSlice("tag", "a tag", "TAG", 1)
+	fs.Pres("v", "verbose")
+	var seen []string
+	fs.Use(func(pf *ParsedFlag) error {
+		seen = append(seen, fmt.Sprintf("%s#%d%q", pf.Flag.Name[0], pf.Index, pf.Tokens))
+		return nil
+	})
+	fs.Use(func(pf *ParsedFlag) error {
+		if pf.Name == "name" {
+			pf.Tokens = []string{strings.ToUpper(pf.Tokens[0])}
+		}
+		if pf.Name == "tag" && pf.Tokens[0] == "bad" {
+			return fmt.Errorf("tag not allowed")
+		}
+		return nil
+	})
+	if err := fs.Parse([]string{"--tag", "a", "-v", "--name", "x", "--tag", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{`tag#0["a"]`, `v#0[]`, `name#0["x"]`, `tag#1["b"]`}
+	if !reflect.DeepEqual(seen, want) {
+		t.Errorf("seen %q; want %q", seen, want)
+	}
+	if *name != "X" {
+		t.Errorf("name = %q; want X from middleware", *name)
+	}
+	// the index restarts with each Parse, and counts only the command line
+	seen = nil
+	fs.Set("tag", []string{"set"})
+	if err := fs.Parse([]string{"--tag", "c"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{`tag#0["c"]`}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("seen %q; want %q", seen, want)
+	}
+	if err := fs.Parse([]string{"--tag", "bad"}); err == nil {
+		t.Error("expected error from middleware")
+	}
+}
+
+type stepValue int
+
+func (s *stepValue) String() string { return strconv.Itoa(int(*s)) }
+
+func (s *stepValue) Set([]string) error { return fmt.Errorf("name needed") }
+
+func (s *stepValue) SetNamed(name string, vals []string) error {
+	switch name {
+	case "increase", "i":
+		*s++
+	case "decrease", "d":
+		*s--
+	}
+	return nil
+}
+
+func TestNamedSetter(t *testing.T) {
+	fs := NewFlagSet("named setter test", ContinueOnError)
+	var step stepValue
+	fs.Var(&step, "i increase", "step up", "", 0)
+	fs.Var(SyncValue(&step), "d decrease", "step down", "", 0)
+	if err := fs.Parse([]string{"--increase", "-ii", "--decrease"}); err != nil {
+		t.Fatal(err)
+	}
+	if step != 2 {
+		t.Errorf("step = %d; want 2", step)
+	}
+	if err := fs.Set("i", nil); err != nil || step != 3 {
+		t.Errorf("Set gave %v and step %d; want nil and 3", err, step)
+	}
+}
+
+func TestTokenizeAndParse(t *testing.T) {
+	fs := NewFlagSet("tokenize test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	fs.SetAllowIntersperse(true)
+	name := fs.String("name", "", "a name", "NAME")
+	for _, test := range []struct {
+		line string
+		name string
+		args []string
+	}{
+		{`--name "a b" x`, "a b", []string{"x"}},
+		{`--name 'a "b"' x`, `a "b"`, []string{"x"}},
+		{`--name a\ b  x\'y`, "a b", []string{"x'y"}},
+		{`--name "say \"hi\" \n" ''`, `say "hi" \n`, []string{""}},
+		{"--name a \\\n b", "a", []string{"b"}},
+		{`--name it\'s' fine'`, "it's fine", nil},
+	} {
+		if err := fs.TokenizeAndParse(test.line); err != nil {
+			t.Errorf("TokenizeAndParse(%q): %v", test.line, err)
+			continue
+		}
+		if *name != test.name || !reflect.DeepEqual(fs.Args(), test.args) {
+			t.Errorf("TokenizeAndParse(%q) gave %q %q; want %q %q", test.line, *name, fs.Args(), test.name, test.args)
+		}
+	}
+	for _, line := range []string{`--name "a`, `--name 'a`, `--name a\`} {
+		if err := fs.TokenizeAndParse(line); err == nil {
+			t.Errorf("TokenizeAndParse(%q): expected error", line)
+		}
+	}
+}
+
+func TestSetPattern(t *testing.T) {
+	fs := NewFlagSet("pattern test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	addr := fs.String("addr", "", "address to listen on", "")
+	fs.SetPattern("addr", `^[^:]*:[0-9]+$`)
+	if err := fs.Parse([]string{"--addr", "localhost:80"}); err != nil || *addr != "localhost:80" {
+		t.Errorf("got %q, %v; want localhost:80", *addr, err)
+	}
+	err := fs.Parse([]string{"--addr", "localhost"})
+	if err == nil || !strings.Contains(err.Error(), "does not match the pattern") {
+		t.Errorf("got error %v; want pattern mismatch", err)
+	}
+	if !strings.Contains(fs.UsageString(), `--addr ^[^:]*:[0-9]+$`) {
+		t.Errorf("pattern not shown as the type expected:\n%s", fs.UsageString())
+	}
+}
+
+func TestDurationZeroDisplay(t *testing.T) {
+	fs := NewFlagSet("duration zero test", ContinueOnError)
+	fs.ShowDefaultVal = true
+	var timeout, wait time.Duration
+	fs.DurationZeroVar(&timeout, "timeout", 0, "none", "time limit", "DURATION")
+	fs.DurationZeroVar(&wait, "wait", 90*time.Second, "none", "time to wait", "DURATION")
+	if got := fs.Lookup("timeout").DefValue; got != "none" {
+		t.Errorf("zero DefValue = %q; want none", got)
+	}
+	if got := fs.Lookup("wait").DefValue; got != "1m30s" {
+		t.Errorf("non-zero DefValue = %q; want 1m30s", got)
+	}
+	if err := fs.Parse([]string{"--timeout", "5s"}); err != nil || timeout != 5*time.Second {
+		t.Errorf("got %v, %v; want 5s", timeout, err)
+	}
+	if !strings.Contains(fs.UsageString(), "(Default: none)") {
+		t.Errorf("expected (Default: none) in:\n%s", fs.UsageString())
+	}
+}
+
+func TestDurationUnits(t *testing.T) {
+	fs := NewFlagSet("duration units test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	var interval time.Duration
+	fs.DurationUnitsVar(&interval, "interval", time.Minute, []string{"s", "m", "h"}, "poll interval", "DURATION")
+	if err := fs.Parse([]string{"--interval", "1h30m"}); err != nil || interval != 90*time.Minute {
+		t.Errorf("got %v, %v; want 1h30m0s", interval, err)
+	}
+	err := fs.Parse([]string{"--interval", "100ms"})
+	if err == nil || !strings.Contains(err.Error(), `unit "ms" not allowed`) {
+		t.Errorf("got error %v; want ms not allowed", err)
+	}
+}
+
+func TestSingleDashLong(t *testing.T) {
+	fs := NewFlagSet("single dash test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	fs.SetSingleDashLong(true)
+	verbose := fs.Pres("verbose", "be verbose")
+	name := fs.String("name", "", "a name", "NAME")
+	x := fs.Pres("x", "x")
+	v := fs.Pres("v", "v")
+	if err := fs.Parse([]string{"-verbose", "-name=a", "-xv"}); err != nil {
+		t.Fatal(err)
+	}
+	if !*verbose || *name != "a" || !*x || !*v {
+		t.Errorf("got verbose=%v name=%q x=%v v=%v", *verbose, *name, *x, *v)
+	}
+	fs = NewFlagSet("single dash test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	fs.Pres("verbose", "be verbose")
+	if err := fs.Parse([]string{"-verbose"}); err == nil {
+		t.Error("expected -verbose to be a cluster when not enabled")
+	}
+}
+
+func TestWrapStdValue(t *testing.T) {
+	std := flag.NewFlagSet("std", flag.ContinueOnError)
+	level := std.Int("level", 3, "level")
+	debug := std.Bool("debug", false, "debug")
+	fs := NewFlagSet("wrap std test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	fs.Var(WrapStdValue(std.Lookup("level").Value), "level", "a level", "N", 1)
+	fs.Var(WrapStdValue(std.Lookup("debug").Value), "debug", "debug", "", 0)
+	if err := fs.Parse([]string{"--level", "5", "--debug"}); err != nil {
+		t.Fatal(err)
+	}
+	if *level != 5 || !*debug {
+		t.Errorf("got level=%d debug=%v; want 5 true", *level, *debug)
+	}
+	if got := fs.Lookup("level").Value.(Getter).Get(); got != 5 {
+		t.Errorf("Get() = %v; want 5", got)
+	}
+	if err := fs.Parse([]string{"--level", "x"}); err == nil {
+		t.Error("expected error for --level x")
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	fs := NewFlagSet("occurrences test", ContinueOnError)
+	fs.Pres("v verbose", "be verbose")
+	fs.String("name", "", "a name", "NAME")
+	fs.Pres("q", "quiet")
+	if err := fs.Parse([]string{"-vv", "--verbose", "--name", "a", "--name", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]int{"v": 3, "verbose": 3, "name": 2, "q": 0, "undefined": 0} {
+		if got := fs.Occurrences(name); got != want {
+			t.Errorf("Occurrences(%q) = %d; want %d", name, got, want)
+		}
+	}
+
+	// each Parse counts afresh, and other ways of setting are not counted
+	t.Setenv("OCCURRENCES_Q", "1")
+	fs.SetEnv("q", "OCCURRENCES_Q")
+	fs.Set("name", []string{"c"})
+	if err := fs.Parse([]string{"-v"}); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]int{"v": 1, "name": 0, "q": 0} {
+		if got := fs.Occurrences(name); got != want {
+			t.Errorf("after second Parse, Occurrences(%q) = %d; want %d", name, got, want)
+		}
+	}
+}
+
+// intGetter is a custom Value with an int value, for TestNegativeValues.
+type intGetter struct{ n int }
+
+func (i *intGetter) Set(s []string) (err error) { i.n, err = strconv.Atoi(s[0]); return }
+func (i *intGetter) String() string             { return strconv.Itoa(i.n) }
+func (i *intGetter) Get() interface{}           { return i.n }
+
+func TestNegativeValues(t *testing.T) {
+	fs := NewFlagSet("negative test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	offset := fs.Duration("offset", 0, "time offset", "DURATION")
+	delay := fs.Duration("delay", 0, "delay", "DURATION")
+	count := fs.Int("count", 0, "count", "N")
+	fs.MarkNonNegative("delay")
+	fs.MarkNonNegative("count")
+	if err := fs.Parse([]string{"--offset", "-5s", "--delay", "5s", "--count", "0"}); err != nil {
+		t.Fatal(err)
+	}
+	if *offset != -5*time.Second || *delay != 5*time.Second || *count != 0 {
+		t.Errorf("got offset=%v delay=%v count=%d", *offset, *delay, *count)
+	}
+	for _, args := range [][]string{{"--delay", "-5s"}, {"--count", "-1"}} {
+		err := fs.Parse(args)
+		if err == nil || !strings.Contains(err.Error(), "must not be negative") {
+			t.Errorf("Parse(%q) error = %v; want must not be negative", args, err)
+		}
+	}
+	if *delay != 5*time.Second || *count != 0 {
+		t.Errorf("after errors got delay=%v count=%d; want 5s 0", *delay, *count)
+	}
+
+	// marking leaves the value alone, and it is put back without reparsing
+	distance := new(float64)
+	fs.UnitFloatVar(distance, "distance", map[string]float64{"km": 1000}, 1000, 1, "distance", "DISTANCE")
+	verbosity := fs.Count("v", "verbosity")
+	fs.Parse([]string{"-v"})
+	fs.MarkNonNegative("distance")
+	fs.MarkNonNegative("v")
+	if *distance != 1 || *verbosity != 1 {
+		t.Errorf("after marking got distance=%v v=%d; want 1 1", *distance, *verbosity)
+	}
+	if err := fs.Parse([]string{"--distance", "-2"}); err == nil || *distance != 1 {
+		t.Errorf("got distance=%v, error %v; want 1 and an error", *distance, err)
+	}
+
+	fs.Uint("size", 0, "size", "N")
+	fs.IntSlice("ports", "ports", "PORT", -1)
+	fs.Var(&intGetter{}, "custom", "custom", "N", 1)
+	for _, name := range []string{"size", "ports", "custom"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("MarkNonNegative(%q) did not panic", name)
+				}
+			}()
+			fs.MarkNonNegative(name)
+		}()
+	}
+}
+
+func TestPresentNoDefault(t *testing.T) {
+	fs := NewFlagSet("present default test", ContinueOnError)
+	fs.ShowDefaultVal = true
+	fs.Pres("quiet", "be quiet")
+	v := fs.Pres("verbose", "be verbose")
+	std := flag.NewFlagSet("std", flag.ContinueOnError)
+	std.Bool("debug", true, "debug")
+	fs.Var(WrapStdValue(std.Lookup("debug").Value), "debug", "debug", "", 0)
+	*v = true
+	var buf bytes.Buffer
+	fs.SetOutput(&buf)
+	fs.PrintDefaults()
+	if strings.Contains(buf.String(), "Default") {
+		t.Errorf("present flags should show no default, got:\n%s", buf.String())
+	}
+}
+
+func TestSliceMutationSyntax(t *testing.T) {
+	for _, test := range []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"--tags", "a", "b", "--tags", "c"}, []string{"a", "b", "c"}},
+		{[]string{"--tags", "a", "--tags", "=b", "c"}, []string{"b", "c"}},
+		{[]string{"--tags", "a", "--tags", "+b"}, []string{"a", "b"}},
+		{[]string{"--tags", "a", "--tags", "=", "--list", "=x,y"}, []string{}},
+	} {
+		fs := NewFlagSet("slice mutation test", ContinueOnError)
+		fs.SetSliceMutationSyntax(true)
+		tags := fs.StringSlice("tags", "tags", "TAG", -1)
+		list := fs.StringList("list", "list", "ITEM")
+		*list = []string{"old"}
+		if err := fs.Parse(test.args); err != nil {
+			t.Errorf("Parse(%q): %v", test.args, err)
+			continue
+		}
+		if strings.Join(*tags, ",") != strings.Join(test.want, ",") {
+			t.Errorf("Parse(%q) tags = %q; want %q", test.args, *tags, test.want)
+		}
+		if test.want != nil && len(test.want) == 0 && strings.Join(*list, ",") != "x,y" {
+			t.Errorf("Parse(%q) list = %q; want [x y]", test.args, *list)
+		}
+	}
+}
+
+func TestArgsFilter(t *testing.T) {
+	fs := NewFlagSet("args filter test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	fs.Pres("v", "verbose")
+	fs.SetArgsFilter(func(args []string) ([]string, error) {
+		var out []string
+		for _, a := range args {
+			if a == "*.go" {
+				out = append(out, "a.go", "b.go")
+			} else if a == "bad" {
+				return nil, fmt.Errorf("bad argument")
+			} else {
+				out = append(out, a)
+			}
+		}
+		return out, nil
+	})
+	if err := fs.Parse([]string{"-v", "x", "*.go"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(fs.Args(), " "); got != "x a.go b.go" {
+		t.Errorf("Args() = %q; want x a.go b.go", got)
+	}
+	if err := fs.Parse([]string{"bad"}); err == nil || err.Error() != "bad argument" {
+		t.Errorf("got error %v; want bad argument", err)
+	}
+	if _, err := fs.ParseUntilOperand([]string{"-v", "sub", "*.go"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(fs.Args(), " "); got != "sub a.go b.go" {
+		t.Errorf("Args() after ParseUntilOperand = %q; want sub a.go b.go", got)
+	}
+}
+
+func TestEmptyValue(t *testing.T) {
+	fs := NewFlagSet("empty value test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	fs.SetAllowIntersperse(true)
+	s := fs.String("string", "x", "string", "S")
+	fs.Bool("bool", false, "bool", "B")
+	fs.Int("int", 0, "int", "N")
+	fs.Int64("int64", 0, "int64", "N")
+	fs.Uint("uint", 0, "uint", "N")
+	fs.Uint64("uint64", 0, "uint64", "N")
+	fs.Float64("float64", 0, "float64", "F")
+	fs.Duration("duration", 0, "duration", "D")
+	fs.OptionalInt("optional", 1, 0, "optional int", "[N]")
+	if err := fs.Parse([]string{"--string=", "next"}); err != nil {
+		t.Fatal(err)
+	}
+	if *s != "" || fs.NArg() != 1 {
+		t.Errorf("--string= gave %q with args %q; want empty and [next]", *s, fs.Args())
+	}
+	for _, name := range []string{"bool", "int", "int64", "uint", "uint64", "float64", "duration", "optional"} {
+		err := fs.Parse([]string{"--" + name + "=", "1"})
+		if want := "empty value for parameter --" + name; err == nil || err.Error() != want {
+			t.Errorf("--%s= gave error %v; want %s", name, err, want)
+		}
+	}
+}
+
+func TestMarkExactlyOne(t *testing.T) {
+	for _, test := range []struct {
+		args []string
+		ok   bool
+	}{
+		{[]string{}, false},
+		{[]string{"--verbose"}, true},
+		{[]string{"--quiet", "--normal"}, false},
+	} {
+		fs := NewFlagSet("exactly one test", ContinueOnError)
+		fs.SetOutput(Discard{})
+		fs.Pres("quiet", "be quiet")
+		fs.Pres("verbose", "be verbose")
+		fs.Pres("normal", "be normal")
+		fs.MarkExactlyOne("quiet", "verbose", "normal")
+		err := fs.Parse(test.args)
+		if (err == nil) != test.ok {
+			t.Errorf("Parse(%q) error = %v; want ok %v", test.args, err, test.ok)
+		}
+		if want := "exactly one of --quiet, --verbose, --normal is required"; err != nil && err.Error() != want {
+			t.Errorf("error = %q; want %q", err, want)
+		}
+	}
+}
+
+func TestAddLazy(t *testing.T) {
+	fs := NewFlagSet("lazy test", ContinueOnError)
+	var level *int
+	calls := 0
+	fs.AddLazy(func(fs *FlagSet) {
+		calls++
+		level = fs.Int("level", 0, "plugin level", "N")
+		fs.AddLazy(func(fs *FlagSet) { calls++ })
+	})
+	if fs.Lookup("level") != nil {
+		t.Fatal("level defined before Parse")
+	}
+	if err := fs.Parse([]string{"--level", "3"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Parse([]string{"--level", "4"}); err != nil {
+		t.Fatal(err)
+	}
+	if *level != 4 || calls != 2 {
+		t.Errorf("got level %d with %d calls; want 4 with 2", *level, calls)
+	}
+}
+
+func TestPrescan(t *testing.T) {
+	fs := NewFlagSet("prescan test", ContinueOnError)
+	fs.String("f format", "text", "output format", "FORMAT")
+	fs.String("o", "", "output file", "FILE")
+	fs.Pres("v", "verbose")
+	fs.StringSlice("tags", "tags", "TAG", -1)
+	args := []string{"-v", "-o", "--format", "--format=json", "-vfyaml", "--tags", "a", "b", "--experimental", "--", "--format=x"}
+	got := fs.Prescan(args, "format", "tags", "v", "experimental", "missing")
+	want := map[string][]string{
+		"format":       {"json", "yaml"},
+		"tags":         {"a", "b"},
+		"v":            {},
+		"experimental": {},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Prescan() = %q; want %q", got, want)
+	}
+	if fs.Parsed() || fs.NFlag() != 0 {
+		t.Error("Prescan changed the flag set")
+	}
+
+	fs.IndexedStringSlice("item", "an item", "ITEM")
+	fs.DefineExpansion("json", []string{"--format", "json", "-v"})
+	got = fs.Prescan([]string{"--item[1]", "b", "-json", "--item[0]=a"}, "item", "format", "v")
+	want = map[string][]string{
+		"item":   {"b", "a"},
+		"format": {"json"},
+		"v":      {},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Prescan() = %q; want %q", got, want)
+	}
+}
+
+func TestAddExample(t *testing.T) {
+	fs := NewFlagSet("prog", ContinueOnError)
+	fs.Pres("z", "compress")
+	fs.AddExample("Compress a file in place:", "prog -z file")
+	fs.AddExample("Read from standard input:", "prog -z -")
+	want := "\nExamples:\n" +
+		"  Compress a file in place:\n    prog -z file\n" +
+		"  Read from standard input:\n    prog -z -\n"
+	if got := fs.UsageString(); !strings.HasSuffix(got, want) {
+		t.Errorf("UsageString() = %q; want suffix %q", got, want)
+	}
+	var buf bytes.Buffer
+	fs.SetOutput(&buf)
+	fs.Parse([]string{"--help"})
+	if !strings.HasSuffix(buf.String(), want) {
+		t.Errorf("help = %q; want suffix %q", buf.String(), want)
+	}
+}
+
+func TestSetEpilogue(t *testing.T) {
+	fs := NewFlagSet("prog", ContinueOnError)
+	fs.Pres("z", "compress")
+	fs.AddExample("Compress:", "prog -z file")
+	fs.Width = 30
+	fs.SetEpilogue("Report bugs to the issue tracker, see the man page for details.")
+	want := "    prog -z file\n\nReport bugs to the issue\ntracker, see the man page for\ndetails.\n"
+	if got := fs.UsageString(); !strings.HasSuffix(got, want) {
+		t.Errorf("UsageString() = %q; want suffix %q", got, want)
+	}
+}
+
+func TestSetDescription(t *testing.T) {
+	fs := NewFlagSet("prog", ContinueOnError)
+	fs.ShowGroupings = false
+	fs.Pres("z", "compress")
+	fs.Width = 30
+	fs.SetDescription("Prog compresses files, replacing each with a smaller one.")
+	want := "Usage: prog [option]\n\nProg compresses files,\nreplacing each with a smaller\none.\n\n  -z"
+	if got := fs.UsageString(); !strings.HasPrefix(got, want) {
+		t.Errorf("UsageString() = %q; want prefix %q", got, want)
+	}
+
+	var buf bytes.Buffer
+	fs.SetOutput(&buf)
+	fs.Parse([]string{"--help"})
+	if got := buf.String(); !strings.HasPrefix(got, want[len("Usage: prog [option]\n\n"):]) {
+		t.Errorf("usage = %q; want the description first", got)
+	}
+}
+
+func TestValueIndirection(t *testing.T) {
+	t.Setenv("TEST_TOKEN", "secret")
+	file := t.TempDir() + "/key"
+	if err := os.WriteFile(file, []byte("key data\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	fs := NewFlagSet("indirection test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	token := fs.String("token", "", "api token", "TOKEN")
+	key := fs.String("key", "", "key", "KEY")
+	if err := fs.Parse([]string{"--token", "@env:TEST_TOKEN", "--key=@file:" + file}); err != nil {
+		t.Fatal(err)
+	}
+	if *token != "@env:TEST_TOKEN" {
+		t.Errorf("token = %q; want it as given when off", *token)
+	}
+	fs.SetValueIndirection(true)
+	if err := fs.Parse([]string{"--token", "@env:TEST_TOKEN", "--key=@file:" + file}); err != nil {
+		t.Fatal(err)
+	}
+	if *token != "secret" || *key != "key data" {
+		t.Errorf("got token %q key %q; want secret and key data", *token, *key)
+	}
+	for _, v := range []string{"@env:TEST_UNSET_VARIABLE", "@file:" + file + ".missing"} {
+		if err := fs.Parse([]string{"--token", v}); err == nil {
+			t.Errorf("expected error for %s", v)
+		}
+	}
+
+
+	// errors show the values as given, not the secrets they resolve to
+	fs.Int("port", 0, "port", "PORT")
+	fs.OptionalInt("level", 1, 0, "level", "[N]")
+	for _, args := range [][]string{{"--port", "@env:TEST_TOKEN"}, {"--level=@env:TEST_TOKEN"}} {
+		err := fs.Parse(args)
+		pe, ok := err.(*ParseError)
+		if !ok || strings.Contains(pe.Error(), "secret") || fmt.Sprint(pe.Value) != "[@env:TEST_TOKEN]" {
+			t.Errorf("Parse(%q) = %#v; want the raw token only", args, err)
+		}
+	}
+}
+
+func TestCheckClusters(t *testing.T) {
+	fs := NewFlagSet("clusters test", ContinueOnError)
+	fs.Pres("a", "a")
+	fs.Pres("b", "b")
+	fs.String("o", "", "output", "FILE")
+	fs.Pres("ab", "confused with -a -b")
+	fs.Pres("ac", "not confused, there is no -c")
+	fs.Pres("out", "confused with -o ut")
+	var got []string
+	for _, err := range fs.CheckClusters() {
+		got = append(got, err.Error())
+	}
+	want := []string{
+		"parameter --ab could be confused with the cluster -ab",
+		"parameter --out could be confused with the cluster -out",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckClusters() = %q; want %q", got, want)
+	}
+}
+
+func TestSetDefaultTemplate(t *testing.T) {
+	fs := NewFlagSet("template test", ContinueOnError)
+	fs.ShowDefaultVal = true
+	fs.String("host", "", "host to use", "HOST")
+	fs.String("user", "nobody", "user to run as", "USER")
+	fs.String("other", "x", "other", "X")
+	data := struct{ Hostname string }{"box"}
+	fs.SetDefaultTemplate("host", "{{.Hostname}}", &data)
+	fs.SetDefaultTemplate("user", "{{.Missing}}", &data)
+	data.Hostname = "late"
+	got := fs.UsageString()
+	for _, want := range []string{`(Default: "late")`, `(Default: "nobody")`, `(Default: "x")`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %s in:\n%s", want, got)
+		}
+	}
+}
+
+func TestHideFalseBoolDefaults(t *testing.T) {
+	fs := NewFlagSet("hide false test", ContinueOnError)
+	fs.ShowDefaultVal = true
+	fs.Bool("color", false, "use color", "BOOL")
+	fs.Bool("cache", true, "use the cache", "BOOL")
+	if got := fs.UsageString(); !strings.Contains(got, "(Default: false)") {
+		t.Errorf("false default hidden when off:\n%s", got)
+	}
+	fs.SetHideFalseBoolDefaults(true)
+	got := fs.UsageString()
+	if strings.Contains(got, "(Default: false)") || !strings.Contains(got, "(Default: true)") {
+		t.Errorf("want only the true default shown:\n%s", got)
+	}
+}
+
+func TestEnforceNamePolicy(t *testing.T) {
+	for name, ok := range map[string]bool{
+		"v": true, "9": true, "dry-run": true, "tls12": true,
+		"-": false, "DryRun": false, "dry_run": false, "dry--run": false, "-x": false,
+	} {
+		if err := KebabCase(name); (err == nil) != ok {
+			t.Errorf("KebabCase(%q) = %v; want ok %v", name, err, ok)
+		}
+	}
+	fs := NewFlagSet("policy test", ContinueOnError)
+	var buf bytes.Buffer
+	fs.SetOutput(&buf)
+	fs.Pres("v verbose", "be verbose")
+	fs.EnforceNamePolicy(KebabCase)
+	fs.Pres("dry-run", "do nothing")
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for DryRun")
+		}
+		if !strings.Contains(buf.String(), "DryRun does not meet the naming policy") {
+			t.Errorf("unexpected message %q", buf.String())
+		}
+	}()
+	fs.Pres("DryRun", "do nothing")
+}
+
+func TestMarkTrimSpace(t *testing.T) {
+	fs := NewFlagSet("trim test", ContinueOnError)
+	file := fs.String("file", "", "file", "FILE")
+	raw := fs.String("raw", "", "raw", "S")
+	tags := fs.StringSlice("tags", "tags", "TAG", -1)
+	fs.MarkTrimSpace("file")
+	fs.MarkTrimSpace("tags")
+	if err := fs.Parse([]string{"--file", " a.txt \n", "--raw", " x ", "--tags", "a ", "\tb"}); err != nil {
+		t.Fatal(err)
+	}
+	if *file != "a.txt" || *raw != " x " || strings.Join(*tags, ",") != "a,b" {
+		t.Errorf("got file %q raw %q tags %q", *file, *raw, *tags)
+	}
+}
+
+func TestUnitFloat(t *testing.T) {
+	units := map[string]float64{"m": 1, "km": 1000, "mm": 0.001}
+	fs := NewFlagSet("unit float test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	var dist float64
+	fs.UnitFloatVar(&dist, "distance", units, 1, 10, "distance to travel", "DISTANCE")
+	if got := fs.Lookup("distance").DefValue; got != "10" {
+		t.Errorf("DefValue = %q; want 10", got)
+	}
+	for in, want := range map[string]float64{"5km": 5000, "2.5 m": 2.5, "300mm": 0.3, "7": 7, "1e3m": 1000} {
+		if err := fs.Parse([]string{"--distance", in}); err != nil {
+			t.Errorf("Parse(%q): %v", in, err)
+		} else if math.Abs(dist-want) > 1e-9 {
+			t.Errorf("Parse(%q) = %v; want %v", in, dist, want)
+		}
+	}
+	err := fs.Parse([]string{"--distance", "5mi"})
+	if err == nil || !strings.Contains(err.Error(), "must be one of: km, m, mm") {
+		t.Errorf("got error %v; want unknown unit", err)
+	}
+}
+
+func TestIntSlice(t *testing.T) {
+	fs := NewFlagSet("int slice test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	ports := fs.IntSlice("ports", "ports to listen on", "PORT", -1)
+	if err := fs.Parse([]string{"--ports", "80", "443", "--ports", "0x1f90"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(*ports); got != "[80 443 8080]" {
+		t.Errorf("ports = %s; want [80 443 8080]", got)
+	}
+	if got := fs.Lookup("ports").Value.String(); got != "[80 443 8080]" {
+		t.Errorf("String() = %s; want [80 443 8080]", got)
+	}
+	err := fs.Parse([]string{"--ports", "22", "ssh"})
+	if err == nil || !strings.Contains(err.Error(), `"ssh"`) {
+		t.Errorf("got error %v; want one naming \"ssh\"", err)
+	}
+}
+
+func TestFloat64Slice(t *testing.T) {
+	fs := NewFlagSet("float64 slice test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	weights := fs.Float64Slice("weights", "weights to apply", "W", -1)
+	if err := fs.Parse([]string{"--weights", "0.1", "0.2", "--weights", "0.7"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(*weights); got != "[0.1 0.2 0.7]" {
+		t.Errorf("weights = %s; want [0.1 0.2 0.7]", got)
+	}
+	err := fs.Parse([]string{"--weights", "1", "heavy"})
+	if err == nil || !strings.Contains(err.Error(), `"heavy"`) {
+		t.Errorf("got error %v; want one naming \"heavy\"", err)
+	}
+}
+
+func TestLintRequiredDefault(t *testing.T) {
+	fs := NewFlagSet("lint required test", ContinueOnError)
+	fs.Flag("host").Usage("host to dial").Type("HOST").String("localhost").Required().Register(new(string))
+	fs.Flag("port").Usage("port to dial").Type("PORT").Int(0).Required().Register(new(int))
+	var got []string
+	for _, err := range fs.LintUsage() {
+		got = append(got, err.Error())
+	}
+	want := `[parameter --host is required and has the default "localhost"]`
+	if fmt.Sprint(got) != want {
+		t.Errorf("LintUsage() = %s; want %s", got, want)
+	}
+}
+
+func TestDurationSlice(t *testing.T) {
+	fs := NewFlagSet("duration slice test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	timeouts := fs.DurationSlice("timeouts", "timeouts to try", "DURATION", -1)
+	if err := fs.Parse([]string{"--timeouts", "1s", "500ms", "--timeouts", "2m"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := fs.Lookup("timeouts").Value.String(); got != "[1s 500ms 2m0s]" {
+		t.Errorf("timeouts = %s; want [1s 500ms 2m0s]", got)
+	}
+	if len(*timeouts) != 3 || (*timeouts)[2] != 2*time.Minute {
+		t.Errorf("timeouts = %v", *timeouts)
+	}
+	err := fs.Parse([]string{"--timeouts", "1s", "soon"})
+	if err == nil || !strings.Contains(err.Error(), `"soon"`) {
+		t.Errorf("got error %v; want one naming \"soon\"", err)
+	}
+}
+
+func TestParseFunc(t *testing.T) {
+	args := []string{"-v", "--name", "x", "--list", "a", "b", "--", "rest", "of"}
+	pulled := 0
+	next := func() (string, bool) {
+		if pulled == len(args) {
+			return "", false
+		}
+		pulled++
+		return args[pulled-1], true
+	}
+	fs := NewFlagSet("parse func test", ContinueOnError)
+	verbose := fs.Pres("v", "verbose")
+	name := fs.String("name", "", "a name", "NAME")
+	list := fs.StringSlice("list", "a list", "ITEM", -1)
+	if err := fs.ParseFunc(next); err != nil {
+		t.Fatal(err)
+	}
+	if !*verbose || *name != "x" || fmt.Sprint(*list) != "[a b]" {
+		t.Errorf("got v %v name %q list %q", *verbose, *name, *list)
+	}
+	if got := fmt.Sprint(fs.Args()); got != "[rest of]" {
+		t.Errorf("Args() = %s; want [rest of]", got)
+	}
+
+	// only the arguments needed are pulled
+	args, pulled = []string{"--name", "y", "--bogus", "never", "read"}, 0
+	fs.SetOutput(Discard{})
+	if err := fs.ParseFunc(next); err == nil {
+		t.Error("expected error for --bogus")
+	}
+	if *name != "y" || pulled != 3 {
+		t.Errorf("got name %q after pulling %d; want y after 3", *name, pulled)
+	}
+}
+
+func TestStringMap(t *testing.T) {
+	fs := NewFlagSet("string map test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	headers := fs.StringMap("header", "headers to send", "KEY=VALUE", 1)
+	err := fs.Parse([]string{"--header", "X-Foo=bar", "--header", "Content-Type=json",
+		"--header", "X-Foo=a=b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*headers) != 2 || (*headers)["X-Foo"] != "a=b" || (*headers)["Content-Type"] != "json" {
+		t.Errorf("headers = %v", *headers)
+	}
+	if got, want := fs.Lookup("header").Value.String(), `["Content-Type=json" "X-Foo=a=b"]`; got != want {
+		t.Errorf("String() = %s; want %s", got, want)
+	}
+	err = fs.Parse([]string{"--header", "X-Bar"})
+	if err == nil || !strings.Contains(err.Error(), `missing = in "X-Bar"`) {
+		t.Errorf("got error %v; want missing =", err)
+	}
+}
+
+func TestMarkExperimental(t *testing.T) {
+	newSet := func() *FlagSet {
+		fs := NewFlagSet("experimental test", ContinueOnError)
+		fs.SetOutput(Discard{})
+		fs.Pres("new-thing", "try the new thing")
+		fs.Pres("old-thing", "use the old thing")
+		fs.MarkExperimental("new-thing")
+		return fs
+	}
+	err := newSet().Parse([]string{"--old-thing", "--new-thing"})
+	want := "parameter --new-thing is experimental; pass --experimental to enable"
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("got error %v; want %q", err, want)
+	}
+	if err := newSet().Parse([]string{"--new-thing", "--experimental"}); err != nil {
+		t.Error(err)
+	}
+	fs := newSet()
+	if help := fs.UsageString(); strings.Contains(help, "new-thing") || strings.Contains(help, "--experimental") {
+		t.Errorf("help shows experimental flags:\n%s", help)
+	}
+}
+
+func TestCount(t *testing.T) {
+	fs := NewFlagSet("count test", ContinueOnError)
+	verbosity := fs.Count("v verbose", "more output, repeat for more")
+	quiet := fs.Pres("q", "less output")
+	if err := fs.Parse([]string{"-vvq", "--verbose", "-v"}); err != nil {
+		t.Fatal(err)
+	}
+	if *verbosity != 4 || !*quiet {
+		t.Errorf("got verbosity %d quiet %v; want 4 true", *verbosity, *quiet)
+	}
+	if help := fs.UsageString(); strings.Contains(help, "Default") {
+		t.Errorf("help shows a default for a count flag:\n%s", help)
+	}
+}
+
+func TestDefaultToken(t *testing.T) {
+	fs := NewFlagSet("default token test", ContinueOnError)
+	fs.String("dir", "/srv", "directory to serve, "+DefaultToken+" if not given", "DIR")
+	fs.Int("port", 80, "port to listen on", "PORT")
+	lines := strings.Join(fs.DefaultLines(), "\n")
+	if !strings.Contains(lines, "directory to serve, /srv if not given\n") {
+		t.Errorf("default not inline:\n%s", lines)
+	}
+	if strings.Contains(lines, `"/srv"`) || !strings.Contains(lines, "(Default: 80)") {
+		t.Errorf("unexpected default annotations:\n%s", lines)
+	}
+
+	var summary, fish bytes.Buffer
+	fs.PrintSummary(&summary)
+	fs.WriteFishCompletion(&fish, "prog")
+	for _, out := range []string{summary.String(), fish.String()} {
+		if !strings.Contains(out, "directory to serve, /srv if not given") {
+			t.Errorf("default not inline:\n%s", out)
+		}
+	}
+}
+
+func TestParseErrorFields(t *testing.T) {
+	fs := NewFlagSet("parse error test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	fs.Int("count", 0, "a count", "N")
+	fs.Flag("name").Usage("a name").Type("NAME").Required().Register(new(string))
+	tests := []struct {
+		args   []string
+		name   string
+		value  string
+		reason string
+		msg    string
+	}{
+		{[]string{"--count", "x"}, "count", "[x]", ReasonInvalid, `invalid value "x" for parameter --count: `},
+		{[]string{"--bogus"}, "bogus", "[]", ReasonUndefined, "parameter provided but not defined: --bogus"},
+		{[]string{"--count"}, "count", "[]", ReasonMissing, "parameter needs an parameter: --count"},
+		{[]string{"--count", "1"}, "name", "[]", ReasonRequired, "required parameter not provided: --name"},
+	}
+	for _, test := range tests {
+		err := fs.Parse(test.args)
+		pe, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("Parse(%q) = %v; want a *ParseError", test.args, err)
+			continue
+		}
+		if pe.Name != test.name || fmt.Sprint(pe.Value) != test.value || pe.Reason != test.reason ||
+			!strings.HasPrefix(pe.Error(), test.msg) {
+			t.Errorf("Parse(%q) = %+v; want name %q value %s reason %q message %q",
+				test.args, pe, test.name, test.value, test.reason, test.msg)
+		}
+	}
+}
+
+func TestParseErrorNames(t *testing.T) {
+	fs := NewFlagSet("parse error names test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	fs.String("cert", "", "a cert", "FILE")
+	fs.String("key", "", "a key", "FILE")
+	fs.String("ca", "", "a ca", "FILE")
+	fs.Pres("quiet", "quiet")
+	fs.Pres("loud", "loud")
+	fs.MarkRequires("cert", "key", "ca")
+	fs.MarkExactlyOne("quiet", "loud")
+	tests := []struct {
+		args   []string
+		names  string
+		reason string
+	}{
+		{[]string{"--quiet", "--cert", "c"}, "[key ca]", ReasonRequires},
+		{[]string{"--quiet", "--loud"}, "[quiet loud]", ReasonExactlyOne},
+	}
+	for _, test := range tests {
+		err := fs.Parse(test.args)
+		pe, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("Parse(%q) = %v; want a *ParseError", test.args, err)
+			continue
+		}
+		if fmt.Sprint(pe.Names) != test.names || pe.Name != pe.Names[0] || pe.Reason != test.reason {
+			t.Errorf("Parse(%q) = %+v; want names %s reason %q", test.args, pe, test.names, test.reason)
+		}
+	}
+
+	fs = NewFlagSet("parse error names test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	fs.String("user", "", "a user", "USER")
+	fs.String("host", "", "a host", "HOST")
+	fs.MarkRequired("user", "host")
+	err := fs.Parse(nil)
+	if pe, ok := err.(*ParseError); !ok || fmt.Sprint(pe.Names) != "[user host]" || pe.Reason != ReasonRequired {
+		t.Errorf("Parse() = %#v; want required user and host", err)
+	}
+
+	// SetFromString returns the same errors as Parse
+	fs = NewFlagSet("parse error names test", ContinueOnError)
+	fs.Int("count", 0, "a count", "N")
+	for _, test := range []struct{ name, raw, reason string }{
+		{"count", "x", ReasonInvalid},
+		{"count", "", ReasonEmpty},
+		{"bogus", "1", ReasonUndefined},
+	} {
+		var pe *ParseError
+		err := fs.SetFromString(test.name, test.raw)
+		if !errors.As(err, &pe) || pe.Name != test.name || pe.Reason != test.reason {
+			t.Errorf("SetFromString(%q, %q) = %v; want a *ParseError with reason %q", test.name, test.raw, err, test.reason)
+		}
+	}
+
+	fs = NewFlagSet("parse error names test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	fs.String("name", "x", "a name", "NAME")
+	fs.Int("count", 0, "a count", "N")
+	fs.SetDefaultFromFlag("count", "name")
+	err = fs.Parse(nil)
+	if pe, ok := err.(*ParseError); !ok || pe.Name != "count" || pe.Reason != ReasonInvalid || pe.Err == nil {
+		t.Errorf("Parse() = %#v; want invalid default for count", err)
+	}
+}
+
+func TestDurationKeywords(t *testing.T) {
+	keywords := map[string]time.Duration{"never": 0, "forever": math.MaxInt64}
+	fs := NewFlagSet("duration keywords test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	var timeout time.Duration
+	fs.DurationKeywordsVar(&timeout, "timeout", 0, keywords, "time to wait", "DURATION")
+	if got := fs.Lookup("timeout").DefValue; got != "never" {
+		t.Errorf("DefValue = %q; want never", got)
+	}
+	for in, want := range map[string]time.Duration{"30s": 30 * time.Second, "forever": math.MaxInt64, "never": 0} {
+		if err := fs.Parse([]string{"--timeout", in}); err != nil {
+			t.Errorf("Parse(%q): %v", in, err)
+		} else if timeout != want {
+			t.Errorf("Parse(%q) = %v; want %v", in, timeout, want)
+		}
+	}
+	err := fs.Parse([]string{"--timeout", "later"})
+	if err == nil || !strings.Contains(err.Error(), "must be a duration or one of: forever, never") {
+		t.Errorf("got error %v; want the keywords listed", err)
+	}
+}
+
+func TestMarkRequired(t *testing.T) {
+	fs := NewFlagSet("required test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	fs.String("env", "", "environment to deploy to", "ENV")
+	fs.String("version", "", "version to deploy", "VERSION")
+	fs.Pres("dry-run", "do nothing")
+	fs.MarkRequired("env", "version")
+	err := fs.Parse([]string{"--dry-run"})
+	if want := "required parameters not provided: --env, --version"; err == nil || err.Error() != want {
+		t.Errorf("got error %v; want %q", err, want)
+	}
+	lines := strings.Join(fs.DefaultLines(), "\n")
+	if strings.Count(lines, "(required)") != 2 {
+		t.Errorf("required flags not marked:\n%s", lines)
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	fs := NewFlagSet("freeze test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	fs.Pres("v", "verbose")
+	fs.AddLazy(func(fs *FlagSet) { fs.Pres("plugin", "defined late") })
+	fs.Freeze()
+	if err := fs.Parse([]string{"-v", "--plugin"}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if r := recover(); r != "parameter set is frozen" {
+			t.Errorf("got panic %v; want parameter set is frozen", r)
+		}
+	}()
+	fs.Pres("late", "defined too late")
+}
+
+func TestSetMaxArgs(t *testing.T) {
+	// an argument file with far too many arguments
+	file := t.TempDir() + "/args"
+	var contents strings.Builder
+	contents.WriteString("--name x\n")
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&contents, "arg%d\n", i)
+	}
+	if err := os.WriteFile(file, []byte(contents.String()), 0600); err != nil {
+		t.Fatal(err)
+	}
+	r, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	scanner := bufio.NewScanner(r)
+	scanner.Split(bufio.ScanWords)
+	var pulled int
+	next := func() (string, bool) {
+		if !scanner.Scan() {
+			return "", false
+		}
+		pulled++
+		return scanner.Text(), true
+	}
+
+	fs := NewFlagSet("max args test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	fs.String("name", "", "a name", "NAME")
+	fs.SetMaxArgs(10)
+	err = fs.ParseFunc(next)
+	if want := "too many arguments, at most 10 allowed"; err == nil || err.Error() != want {
+		t.Errorf("got error %v; want %q", err, want)
+	}
+	if pulled > 13 {
+		t.Errorf("pulled %d arguments; want no more than 13", pulled)
+	}
+
+	fs.SetAllowIntersperse(true)
+	if err := fs.Parse([]string{"a", "--name", "y", "b", "c"}); err != nil {
+		t.Error(err)
+	}
+	fs.SetMaxArgs(2)
+	if err := fs.Parse([]string{"a", "--name", "y", "b", "c"}); err == nil {
+		t.Error("expected error for 3 arguments")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	fs := NewFlagSet("resolve test", ContinueOnError)
+	fs.Pres("v verbose", "verbose output")
+	fs.Pres("x", "extract")
+	fs.String("name", "", "a name", "NAME")
+	verbose, name := fs.Lookup("v"), fs.Lookup("name")
+	tests := map[string]*Flag{
+		"--verbose": verbose, "-v": verbose, "-vx": verbose,
+		"--name=x": name, "-name": nil, "--nope": nil, "name": nil, "--": nil, "-": nil,
+	}
+	for token, want := range tests {
+		if got, ok := fs.Resolve(token); got != want || ok != (want != nil) {
+			t.Errorf("Resolve(%q) = %v, %v; want %v", token, got, ok, want)
+		}
+	}
+	fs.SetSingleDashLong(true)
+	if got, _ := fs.Resolve("-name=x"); got != name {
+		t.Errorf("Resolve(-name=x) = %v; want --name", got)
+	}
+	fs.IndexedStringSlice("item", "an item", "ITEM")
+	fs.DefineExpansion("O2", []string{"--name", "fast", "-x"})
+	item := fs.Lookup("item")
+	for token, want := range map[string]*Flag{"--item[1]": item, "--item[1]=a": item, "-O2": name, "--O2=x": nil} {
+		if got, ok := fs.Resolve(token); got != want || ok != (want != nil) {
+			t.Errorf("Resolve(%q) = %v, %v; want %v", token, got, ok, want)
+		}
+	}
+}
+
+func TestMarkRequires(t *testing.T) {
+	newSet := func() *FlagSet {
+		fs := NewFlagSet("requires test", ContinueOnError)
+		fs.SetOutput(Discard{})
+		fs.String("tls-cert", "", "certificate file", "FILE")
+		fs.String("tls-key", "", "key file", "FILE")
+		fs.String("tls-ca", "", "CA file", "FILE")
+		fs.Pres("mtls", "mutual TLS")
+		fs.MarkRequires("tls-cert", "tls-key")
+		fs.MarkRequires("mtls", "tls-cert", "tls-ca")
+		return fs
+	}
+	err := newSet().Parse([]string{"--tls-cert", "c.pem", "--mtls"})
+	if want := "--tls-cert requires --tls-key; --mtls requires --tls-ca"; err == nil || err.Error() != want {
+		t.Errorf("got error %v; want %q", err, want)
+	}
+	if err := newSet().Parse([]string{"--tls-cert", "c.pem", "--tls-key", "k.pem"}); err != nil {
+		t.Error(err)
+	}
+	if err := newSet().Parse([]string{"--tls-key", "k.pem"}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestIntRange(t *testing.T) {
+	fs := NewFlagSet("int range test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	var workers int
+	var size int64
+	var retries uint
+	fs.IntRangeVar(&workers, "workers", 4, 1, 64, "number of workers", "N")
+	fs.Int64RangeVar(&size, "size", 0, -10, 10, "size", "N")
+	fs.UintRangeVar(&retries, "retries", 3, 0, 5, "number of retries", "N")
+	if err := fs.Parse([]string{"--workers", "64", "--size", "-10", "--retries", "0"}); err != nil {
+		t.Fatal(err)
+	}
+	if workers != 64 || size != -10 || retries != 0 {
+		t.Errorf("got workers %d size %d retries %d", workers, size, retries)
+	}
+	err := fs.Parse([]string{"--workers", "100"})
+	if want := "value 100 out of range [1,64]"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("got error %v; want %q", err, want)
+	}
+	if workers != 64 {
+		t.Errorf("workers = %d after error; want 64", workers)
+	}
+	if lines := strings.Join(fs.DefaultLines(), "\n"); !strings.Contains(lines, "(Default: 4) (Range: [1,64])") {
+		t.Errorf("range not shown:\n%s", lines)
+	}
+}
+
+func TestWriteINIDefaults(t *testing.T) {
+	fs := NewFlagSet("ini test", ContinueOnError)
+	fs.Int("p port", 8080, "port to listen on", "PORT")
+	fs.String("name", "", "a name", "NAME")
+	fs.GroupingSet("Log")
+	fs.String("log-level", "info", "level to log at", "LEVEL")
+	fs.Flag("secret").Hidden().Register(new(string))
+	fs.GroupingSet("")
+	fs.String("dir", "", "directory to serve, "+DefaultToken+" if not given", "DIR")
+	fs.SetDefaultTemplate("dir", "{{.}}/srv", "$HOME")
+	var buf bytes.Buffer
+	fs.WriteINIDefaults(&buf)
+	want := `# directory to serve, $HOME/srv if not given
+dir = $HOME/srv
+
+# a name
+# name = 
+
+# port to listen on
+port = 8080
+
+[Log]
+
+# level to log at
+log-level = info
+
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// flags in no group come before any section, even if defined after
+	fs = NewFlagSet("ini test", ContinueOnError)
+	fs.GroupingSet("Net")
+	fs.Int("alpha", 1, "alpha", "N")
+	fs.GroupingSet("")
+	fs.Int("zeta", 2, "zeta", "N")
+	buf.Reset()
+	fs.WriteINIDefaults(&buf)
+	want = "# zeta\nzeta = 2\n\n[Net]\n\n# alpha\nalpha = 1\n\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestStringEnum(t *testing.T) {
+	fs := NewFlagSet("string enum test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	var level string
+	fs.EnumVar(&level, "loglevel", []string{"debug", "info", "warn", "error"}, "info", "level to log at", "LEVEL")
+	if err := fs.Parse([]string{"--loglevel", "warn"}); err != nil || level != "warn" {
+		t.Errorf("got %q, %v; want warn", level, err)
+	}
+	err := fs.Parse([]string{"--loglevel", "WARN"})
+	if want := "must be one of: debug, info, warn, error"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("got error %v; want %q", err, want)
+	}
+	lines := strings.Join(fs.DefaultLines(), "\n")
+	if !strings.Contains(lines, "(one of: debug,info,warn,error)") {
+		t.Errorf("choices not shown:\n%s", lines)
+	}
+	if !strings.Contains(lines, `(Default: "info")`) {
+		t.Errorf("default not quoted:\n%s", lines)
+	}
+}
+
+func TestIndexedStringSlice(t *testing.T) {
+	fs := NewFlagSet("indexed test", ContinueOnError)
+	fs.SetOutput(Discard{})
+	items := fs.IndexedStringSlice("item", "an item", "ITEM")
+	fs.String("name", "", "a name", "NAME")
+	err := fs.Parse([]string{"--item[2]", "c", "--item[0]=a", "--item", "d", "--item[1]", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(*items); got != "[a b c d]" {
+		t.Errorf("items = %s; want [a b c d]", got)
+	}
+	err = fs.Parse([]string{"--item[-1]", "x"})
+	if err == nil || !strings.Contains(err.Error(), "index -1 is negative") {
+		t.Errorf("got error %v; want negative index", err)
+	}
+	err = fs.Parse([]string{"--name[0]", "x"})
+	if err == nil || !strings.Contains(err.Error(), "not defined: --name[0]") {
+		t.Errorf("got error %v; want not defined", err)
+	}
+}
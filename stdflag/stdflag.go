@@ -0,0 +1,335 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package stdflag mirrors the API of the standard library flag package on
+// top of params, so a program can move over one flag at a time.  Flags are
+// defined with the standard signatures, without the type expected, and
+// their values are parsed exactly as the flag package would.
+//
+// Long names may be given with one dash or two, so -verbose and --verbose
+// are the same.  Bool flags are switches, set to true when given; unlike
+// the flag package, -verbose=false is not accepted.
+//
+// Once moved over, the underlying params.FlagSet is available as Params to
+// use the GNU style features.
+package stdflag
+
+import (
+	"flag"
+	"os"
+	"time"
+
+	"github.com/pschou/go-params"
+)
+
+// A FlagSet is a set of flags defined with the standard library signatures.
+type FlagSet struct {
+	Params *params.FlagSet // the set the flags are defined in
+	std    *flag.FlagSet   // creates the values, to parse as the flag package
+}
+
+// CommandLine is the default set of command-line flags, parsed from os.Args.
+// It defines its flags in params.CommandLine.
+var CommandLine = &FlagSet{Params: params.CommandLine}
+
+// NewFlagSet returns a new, empty flag set with the specified name and error
+// handling property.
+func NewFlagSet(name string, errorHandling flag.ErrorHandling) *FlagSet {
+	f := &FlagSet{Params: params.NewFlagSet(name, params.ErrorHandling(errorHandling))}
+	f.Params.SetSingleDashLong(true)
+	return f
+}
+
+// define adds the flag of the given name, created in f.std, to f.Params.
+func (f *FlagSet) define(name string) {
+	sf := f.std.Lookup(name)
+	typeExp, usage := flag.UnquoteUsage(sf)
+	argsNeeded := 1
+	if b, ok := sf.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+		argsNeeded = 0
+	}
+	f.Params.Var(params.WrapStdValue(sf.Value), name, usage, typeExp, argsNeeded)
+}
+
+// flags returns the flag.FlagSet used to create the values.
+func (f *FlagSet) flags() *flag.FlagSet {
+	if f.std == nil {
+		f.std = flag.NewFlagSet(f.Params.Name(), flag.ContinueOnError)
+	}
+	return f.std
+}
+
+// BoolVar defines a bool flag with specified name, default value, and usage string.
+func (f *FlagSet) BoolVar(p *bool, name string, value bool, usage string) {
+	f.flags().BoolVar(p, name, value, usage)
+	f.define(name)
+}
+
+// Bool defines a bool flag with specified name, default value, and usage string.
+func (f *FlagSet) Bool(name string, value bool, usage string) *bool {
+	p := new(bool)
+	f.BoolVar(p, name, value, usage)
+	return p
+}
+
+// IntVar defines an int flag with specified name, default value, and usage string.
+func (f *FlagSet) IntVar(p *int, name string, value int, usage string) {
+	f.flags().IntVar(p, name, value, usage)
+	f.define(name)
+}
+
+// Int defines an int flag with specified name, default value, and usage string.
+func (f *FlagSet) Int(name string, value int, usage string) *int {
+	p := new(int)
+	f.IntVar(p, name, value, usage)
+	return p
+}
+
+// Int64Var defines an int64 flag with specified name, default value, and usage string.
+func (f *FlagSet) Int64Var(p *int64, name string, value int64, usage string) {
+	f.flags().Int64Var(p, name, value, usage)
+	f.define(name)
+}
+
+// Int64 defines an int64 flag with specified name, default value, and usage string.
+func (f *FlagSet) Int64(name string, value int64, usage string) *int64 {
+	p := new(int64)
+	f.Int64Var(p, name, value, usage)
+	return p
+}
+
+// UintVar defines a uint flag with specified name, default value, and usage string.
+func (f *FlagSet) UintVar(p *uint, name string, value uint, usage string) {
+	f.flags().UintVar(p, name, value, usage)
+	f.define(name)
+}
+
+// Uint defines a uint flag with specified name, default value, and usage string.
+func (f *FlagSet) Uint(name string, value uint, usage string) *uint {
+	p := new(uint)
+	f.UintVar(p, name, value, usage)
+	return p
+}
+
+// Uint64Var defines a uint64 flag with specified name, default value, and usage string.
+func (f *FlagSet) Uint64Var(p *uint64, name string, value uint64, usage string) {
+	f.flags().Uint64Var(p, name, value, usage)
+	f.define(name)
+}
+
+// Uint64 defines a uint64 flag with specified name, default value, and usage string.
+func (f *FlagSet) Uint64(name string, value uint64, usage string) *uint64 {
+	p := new(uint64)
+	f.Uint64Var(p, name, value, usage)
+	return p
+}
+
+// StringVar defines a string flag with specified name, default value, and usage string.
+func (f *FlagSet) StringVar(p *string, name string, value string, usage string) {
+	f.flags().StringVar(p, name, value, usage)
+	f.define(name)
+}
+
+// String defines a string flag with specified name, default value, and usage string.
+func (f *FlagSet) String(name string, value string, usage string) *string {
+	p := new(string)
+	f.StringVar(p, name, value, usage)
+	return p
+}
+
+// Float64Var defines a float64 flag with specified name, default value, and usage string.
+func (f *FlagSet) Float64Var(p *float64, name string, value float64, usage string) {
+	f.flags().Float64Var(p, name, value, usage)
+	f.define(name)
+}
+
+// Float64 defines a float64 flag with specified name, default value, and usage string.
+func (f *FlagSet) Float64(name string, value float64, usage string) *float64 {
+	p := new(float64)
+	f.Float64Var(p, name, value, usage)
+	return p
+}
+
+// DurationVar defines a time.Duration flag with specified name, default value, and usage string.
+func (f *FlagSet) DurationVar(p *time.Duration, name string, value time.Duration, usage string) {
+	f.flags().DurationVar(p, name, value, usage)
+	f.define(name)
+}
+
+// Duration defines a time.Duration flag with specified name, default value, and usage string.
+func (f *FlagSet) Duration(name string, value time.Duration, usage string) *time.Duration {
+	p := new(time.Duration)
+	f.DurationVar(p, name, value, usage)
+	return p
+}
+
+// Var defines a flag with the specified name and usage string, with the type
+// and value given by a flag.Value.
+func (f *FlagSet) Var(value flag.Value, name string, usage string) {
+	f.flags().Var(value, name, usage)
+	f.define(name)
+}
+
+// Func defines a flag with the specified name and usage string.  Each time the
+// flag is seen, fn is called with the value of the flag.
+func (f *FlagSet) Func(name, usage string, fn func(string) error) {
+	f.Var(funcValue(fn), name, usage)
+}
+
+type funcValue func(string) error
+
+func (f funcValue) Set(s string) error { return f(s) }
+
+func (f funcValue) String() string { return "" }
+
+// Parse parses flag definitions from the argument list, which should not
+// include the command name.
+func (f *FlagSet) Parse(arguments []string) error {
+	return f.Params.Parse(arguments)
+}
+
+// Set sets the value of the named flag.
+func (f *FlagSet) Set(name, value string) error {
+	return f.Params.Set(name, []string{value})
+}
+
+// Parsed reports whether f.Parse has been called.
+func (f *FlagSet) Parsed() bool { return f.Params.Parsed() }
+
+// Args returns the non-flag arguments.
+func (f *FlagSet) Args() []string { return f.Params.Args() }
+
+// NArg is the number of arguments remaining after flags have been processed.
+func (f *FlagSet) NArg() int { return f.Params.NArg() }
+
+// Arg returns the i'th argument.  Arg(0) is the first remaining argument
+// after flags have been processed.
+func (f *FlagSet) Arg(i int) string { return f.Params.Arg(i) }
+
+// NFlag returns the number of flags that have been set.
+func (f *FlagSet) NFlag() int { return f.Params.NFlag() }
+
+// PrintDefaults prints the default values of all defined flags in the set.
+func (f *FlagSet) PrintDefaults() { f.Params.PrintDefaults() }
+
+// BoolVar defines a bool command-line flag with specified name, default value, and usage string.
+func BoolVar(p *bool, name string, value bool, usage string) {
+	CommandLine.BoolVar(p, name, value, usage)
+}
+
+// Bool defines a bool command-line flag with specified name, default value, and usage string.
+func Bool(name string, value bool, usage string) *bool {
+	return CommandLine.Bool(name, value, usage)
+}
+
+// IntVar defines an int command-line flag with specified name, default value, and usage string.
+func IntVar(p *int, name string, value int, usage string) {
+	CommandLine.IntVar(p, name, value, usage)
+}
+
+// Int defines an int command-line flag with specified name, default value, and usage string.
+func Int(name string, value int, usage string) *int {
+	return CommandLine.Int(name, value, usage)
+}
+
+// Int64Var defines an int64 command-line flag with specified name, default value, and usage string.
+func Int64Var(p *int64, name string, value int64, usage string) {
+	CommandLine.Int64Var(p, name, value, usage)
+}
+
+// Int64 defines an int64 command-line flag with specified name, default value, and usage string.
+func Int64(name string, value int64, usage string) *int64 {
+	return CommandLine.Int64(name, value, usage)
+}
+
+// UintVar defines a uint command-line flag with specified name, default value, and usage string.
+func UintVar(p *uint, name string, value uint, usage string) {
+	CommandLine.UintVar(p, name, value, usage)
+}
+
+// Uint defines a uint command-line flag with specified name, default value, and usage string.
+func Uint(name string, value uint, usage string) *uint {
+	return CommandLine.Uint(name, value, usage)
+}
+
+// Uint64Var defines a uint64 command-line flag with specified name, default value, and usage string.
+func Uint64Var(p *uint64, name string, value uint64, usage string) {
+	CommandLine.Uint64Var(p, name, value, usage)
+}
+
+// Uint64 defines a uint64 command-line flag with specified name, default value, and usage string.
+func Uint64(name string, value uint64, usage string) *uint64 {
+	return CommandLine.Uint64(name, value, usage)
+}
+
+// StringVar defines a string command-line flag with specified name, default value, and usage string.
+func StringVar(p *string, name string, value string, usage string) {
+	CommandLine.StringVar(p, name, value, usage)
+}
+
+// String defines a string command-line flag with specified name, default value, and usage string.
+func String(name string, value string, usage string) *string {
+	return CommandLine.String(name, value, usage)
+}
+
+// Float64Var defines a float64 command-line flag with specified name, default value, and usage string.
+func Float64Var(p *float64, name string, value float64, usage string) {
+	CommandLine.Float64Var(p, name, value, usage)
+}
+
+// Float64 defines a float64 command-line flag with specified name, default value, and usage string.
+func Float64(name string, value float64, usage string) *float64 {
+	return CommandLine.Float64(name, value, usage)
+}
+
+// DurationVar defines a time.Duration command-line flag with specified name, default value, and usage string.
+func DurationVar(p *time.Duration, name string, value time.Duration, usage string) {
+	CommandLine.DurationVar(p, name, value, usage)
+}
+
+// Duration defines a time.Duration command-line flag with specified name, default value, and usage string.
+func Duration(name string, value time.Duration, usage string) *time.Duration {
+	return CommandLine.Duration(name, value, usage)
+}
+
+// Var defines a command-line flag with the specified name and usage string,
+// with the type and value given by a flag.Value.
+func Var(value flag.Value, name string, usage string) {
+	CommandLine.Var(value, name, usage)
+}
+
+// Func defines a command-line flag with the specified name and usage string.
+// Each time the flag is seen, fn is called with the value of the flag.
+func Func(name, usage string, fn func(string) error) {
+	CommandLine.Func(name, usage, fn)
+}
+
+// Parse parses the command-line flags from os.Args[1:].  Long names may be
+// given with one dash or two.
+func Parse() {
+	CommandLine.Params.SetSingleDashLong(true)
+	// Ignore errors; CommandLine is set for ExitOnError.
+	CommandLine.Parse(os.Args[1:])
+}
+
+// Set sets the value of the named command-line flag.
+func Set(name, value string) error { return CommandLine.Set(name, value) }
+
+// Parsed reports whether the command-line flags have been parsed.
+func Parsed() bool { return CommandLine.Parsed() }
+
+// Args returns the non-flag command-line arguments.
+func Args() []string { return CommandLine.Args() }
+
+// NArg is the number of arguments remaining after flags have been processed.
+func NArg() int { return CommandLine.NArg() }
+
+// Arg returns the i'th command-line argument.
+func Arg(i int) string { return CommandLine.Arg(i) }
+
+// NFlag returns the number of command-line flags that have been set.
+func NFlag() int { return CommandLine.NFlag() }
+
+// PrintDefaults prints the default values of all defined command-line flags.
+func PrintDefaults() { CommandLine.PrintDefaults() }
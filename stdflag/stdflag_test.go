@@ -0,0 +1,43 @@
+// Copyright 2009 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package stdflag_test
+
+import (
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pschou/go-params/stdflag"
+)
+
+func TestStdSignatures(t *testing.T) {
+	fs := stdflag.NewFlagSet("stdflag test", flag.ContinueOnError)
+	verbose := fs.Bool("verbose", false, "be verbose")
+	n := fs.Int("n", 1, "the `count` to use")
+	name := fs.String("name", "", "a name")
+	wait := fs.Duration("wait", 0, "time to wait")
+	var seen []string
+	fs.Func("each", "called each time", func(s string) error {
+		seen = append(seen, s)
+		return nil
+	})
+	err := fs.Parse([]string{"-verbose", "-n", "0x10", "--name=x", "-wait", "2s", "-each", "a", "--each", "b", "rest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !*verbose || *n != 16 || *name != "x" || *wait != 2*time.Second {
+		t.Errorf("got verbose=%v n=%d name=%q wait=%v", *verbose, *n, *name, *wait)
+	}
+	if strings.Join(seen, ",") != "a,b" || fs.NArg() != 1 || fs.Arg(0) != "rest" {
+		t.Errorf("got seen=%q args=%q", seen, fs.Args())
+	}
+	if typeExp := fs.Params.Lookup("n").TypeExpected; typeExp != "count" {
+		t.Errorf("type expected = %q; want count from the usage", typeExp)
+	}
+	if err := fs.Set("n", "x"); err == nil {
+		t.Error("expected error setting n to x")
+	}
+}